@@ -26,6 +26,8 @@ var (
 	Status        LoadingStatus
 	statusMutex   sync.RWMutex
 	Client        = &http.Client{Timeout: 10 * time.Second}
+	loadHooks     []func()
+	hooksMutex    sync.Mutex
 )
 
 type LoadingStatus struct {
@@ -143,9 +145,28 @@ func InitializeData() []error {
 	if len(errors) > 0 {
 		return errors
 	}
+	runLoadHooks()
 	return nil
 }
 
+// OnDataLoaded registers fn to run each time InitializeData has loaded
+// all the data, before it returns. Use it to rebuild anything derived
+// from the data, such as the search index.
+func OnDataLoaded(fn func()) {
+	hooksMutex.Lock()
+	defer hooksMutex.Unlock()
+	loadHooks = append(loadHooks, fn)
+}
+
+func runLoadHooks() {
+	hooksMutex.Lock()
+	hooks := append([]func(){}, loadHooks...)
+	hooksMutex.Unlock()
+	for _, fn := range hooks {
+		fn()
+	}
+}
+
 func FetchArtistsWithContext(ctx context.Context) ([]models.Artists, error) {
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, ARTISTS_API, nil)
 	if err != nil {
@@ -443,6 +443,42 @@ func TestInitializeData_AllSuccess(t *testing.T) {
 	}
 }
 
+// TestInitializeData_RunsLoadHooks tests that hooks registered with
+// OnDataLoaded run after a full load, and not after a failed one.
+func TestInitializeData_RunsLoadHooks(t *testing.T) {
+	reset, restore := setupTest()
+	defer restore()
+	reset()
+	originalHooks := loadHooks
+	defer func() { loadHooks = originalHooks }()
+	loadHooks = nil
+
+	calls, artists := 0, 0
+	OnDataLoaded(func() {
+		calls++
+		artists = len(All_Artists)
+	})
+
+	restoreTransport := setMockTransport(failOneEndpoint("/api/dates"))
+	InitializeData()
+	restoreTransport()
+	if calls != 0 {
+		t.Errorf("Expected no hook calls after a failed load, got %d", calls)
+	}
+
+	restoreTransport = setMockTransport(successTransport())
+	defer restoreTransport()
+	if errs := InitializeData(); errs != nil {
+		t.Fatalf("Expected no errors, got: %v", errs)
+	}
+	if calls != 1 {
+		t.Errorf("Expected 1 hook call, got %d", calls)
+	}
+	if artists == 0 {
+		t.Error("Expected the data to be loaded when the hook runs")
+	}
+}
+
 // TestInitializeData_PartialFailure tests that InitializeData handles
 // partial failures gracefully. When one endpoint fails, others should still load.
 func TestInitializeData_PartialFailure(t *testing.T) {
@@ -0,0 +1,131 @@
+package export
+
+import (
+	"encoding/json"
+	"groupie-tracker/geo"
+	"io"
+	"math"
+	"time"
+)
+
+// routeStepKm is the spacing of the points a route is densified with, so
+// map tools that draw straight lines still follow the great circle.
+const routeStepKm = 100
+
+// Stop is a mapped concert in a tour.
+type Stop struct {
+	Order      int // position among all of the artist's concerts, from 1
+	Tour       int // number of the tour the concert belongs to, 0 if unknown
+	ArtistID   int
+	ArtistName string
+	Location   string
+	Date       time.Time
+	Lat, Lon   float64
+}
+
+// FeatureCollection is a GeoJSON (RFC 7946) document.
+type FeatureCollection struct {
+	Type     string    `json:"type"`
+	Features []Feature `json:"features"`
+}
+
+// Feature is a GeoJSON feature.
+type Feature struct {
+	Type       string         `json:"type"`
+	Geometry   Geometry       `json:"geometry"`
+	Properties map[string]any `json:"properties"`
+}
+
+// Geometry is a GeoJSON geometry. Coordinates are [longitude, latitude].
+type Geometry struct {
+	Type        string `json:"type"`
+	Coordinates any    `json:"coordinates"`
+}
+
+// StopFeatures returns a point feature per stop.
+func StopFeatures(stops []Stop) FeatureCollection {
+	fc := FeatureCollection{Type: "FeatureCollection", Features: []Feature{}}
+	for _, s := range stops {
+		fc.Features = append(fc.Features, Feature{
+			Type:     "Feature",
+			Geometry: Geometry{Type: "Point", Coordinates: []float64{s.Lon, s.Lat}},
+			Properties: map[string]any{
+				"order":    s.Order,
+				"date":     s.Date.Format("2006-01-02"),
+				"location": s.Location,
+				"artist":   s.ArtistName,
+				"artistId": s.ArtistID,
+			},
+		})
+	}
+	return fc
+}
+
+// TourFeatures returns the stops of an artist, in order, followed by a
+// route along great circles for each run of stops in the same tour, so that
+// the trip home between tours isn't drawn. A route crossing the antimeridian
+// is a MultiLineString split at ±180 longitude.
+func TourFeatures(stops []Stop) FeatureCollection {
+	fc := StopFeatures(stops)
+	start := 0
+	for i := 1; i <= len(stops); i++ {
+		if i == len(stops) || stops[i].Tour != stops[start].Tour {
+			if route, ok := routeFeature(stops[start:i]); ok {
+				fc.Features = append(fc.Features, route)
+			}
+			start = i
+		}
+	}
+	return fc
+}
+
+// routeFeature returns the route through stops, if there are two or more.
+func routeFeature(stops []Stop) (Feature, bool) {
+	if len(stops) < 2 {
+		return Feature{}, false
+	}
+	var path []geo.Point
+	for i := 1; i < len(stops); i++ {
+		a := geo.Point{Lat: stops[i-1].Lat, Lon: stops[i-1].Lon}
+		b := geo.Point{Lat: stops[i].Lat, Lon: stops[i].Lon}
+		leg := geo.GreatCircle(a, b, routeStepKm)
+		if i > 1 {
+			leg = leg[1:] // the previous leg already ends here
+		}
+		path = append(path, leg...)
+	}
+
+	var lines [][][]float64
+	for _, part := range geo.SplitAtAntimeridian(path) {
+		line := make([][]float64, 0, len(part))
+		for _, p := range part {
+			line = append(line, []float64{round6(p.Lon), round6(p.Lat)})
+		}
+		lines = append(lines, line)
+	}
+	geometry := Geometry{Type: "MultiLineString", Coordinates: lines}
+	if len(lines) == 1 {
+		geometry = Geometry{Type: "LineString", Coordinates: lines[0]}
+	}
+	properties := map[string]any{
+		"artist":   stops[0].ArtistName,
+		"artistId": stops[0].ArtistID,
+		"from":     stops[0].Date.Format("2006-01-02"),
+		"to":       stops[len(stops)-1].Date.Format("2006-01-02"),
+	}
+	if stops[0].Tour > 0 {
+		properties["tour"] = stops[0].Tour
+	}
+	return Feature{Type: "Feature", Geometry: geometry, Properties: properties}, true
+}
+
+// round6 keeps about 10 cm of precision, which is plenty and keeps the
+// document small.
+func round6(v float64) float64 {
+	return math.Round(v*1e6) / 1e6
+}
+
+// WriteGeoJSON encodes fc.
+func WriteGeoJSON(w io.Writer, fc FeatureCollection) error {
+	return json.NewEncoder(w).Encode(fc)
+}
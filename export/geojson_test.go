@@ -0,0 +1,128 @@
+package export
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func stop(order int, loc string, lat, lon float64, day int) Stop {
+	return Stop{
+		Order: order, ArtistID: 1, ArtistName: "Queen", Location: loc,
+		Date: time.Date(2020, 1, day, 0, 0, 0, 0, time.UTC), Lat: lat, Lon: lon,
+	}
+}
+
+func TestStopFeatures(t *testing.T) {
+	fc := StopFeatures([]Stop{stop(3, "paris-france", 48.85, 2.35, 5)})
+	if len(fc.Features) != 1 {
+		t.Fatalf("got %d features, want 1", len(fc.Features))
+	}
+	f := fc.Features[0]
+	coords := f.Geometry.Coordinates.([]float64)
+	if f.Geometry.Type != "Point" || coords[0] != 2.35 || coords[1] != 48.85 {
+		t.Errorf("geometry = %s %v, want Point [lon, lat]", f.Geometry.Type, coords)
+	}
+	if f.Properties["order"] != 3 || f.Properties["date"] != "2020-01-05" || f.Properties["location"] != "paris-france" {
+		t.Errorf("properties = %v", f.Properties)
+	}
+}
+
+func TestTourFeaturesLineString(t *testing.T) {
+	fc := TourFeatures([]Stop{
+		stop(1, "paris-france", 48.85, 2.35, 1),
+		stop(2, "berlin-germany", 52.52, 13.40, 3),
+		stop(3, "warsaw-poland", 52.23, 21.01, 5),
+	})
+	if len(fc.Features) != 4 {
+		t.Fatalf("got %d features, want 3 points and a route", len(fc.Features))
+	}
+	route := fc.Features[3]
+	if route.Geometry.Type != "LineString" {
+		t.Fatalf("route type = %s, want LineString", route.Geometry.Type)
+	}
+	line := route.Geometry.Coordinates.([][]float64)
+	first, last := line[0], line[len(line)-1]
+	if first[0] != 2.35 || first[1] != 48.85 || last[0] != 21.01 || last[1] != 52.23 {
+		t.Errorf("route runs %v to %v, want Paris to Warsaw", first, last)
+	}
+	if len(line) < 10 {
+		t.Errorf("route has %d points, want it densified", len(line))
+	}
+	if route.Properties["from"] != "2020-01-01" || route.Properties["to"] != "2020-01-05" {
+		t.Errorf("route properties = %v", route.Properties)
+	}
+}
+
+func TestTourFeaturesRoutePerTour(t *testing.T) {
+	stops := []Stop{
+		stop(1, "paris-france", 48.85, 2.35, 1),
+		stop(2, "berlin-germany", 52.52, 13.40, 3),
+		stop(3, "tokyo-japan", 35.68, 139.69, 20),
+		stop(4, "osaka-japan", 34.69, 135.50, 22),
+		stop(5, "lima-peru", -12.05, -77.04, 30),
+	}
+	for i, tour := range []int{1, 1, 2, 2, 3} {
+		stops[i].Tour = tour
+	}
+	fc := TourFeatures(stops)
+	routes := fc.Features[len(stops):]
+	if len(routes) != 2 {
+		t.Fatalf("got %d routes, want one per tour with two stops or more", len(routes))
+	}
+	for i, want := range []struct {
+		tour     int
+		from, to string
+	}{{1, "2020-01-01", "2020-01-03"}, {2, "2020-01-20", "2020-01-22"}} {
+		p := routes[i].Properties
+		if p["tour"] != want.tour || p["from"] != want.from || p["to"] != want.to {
+			t.Errorf("route %d properties = %v, want tour %d from %s to %s", i, p, want.tour, want.from, want.to)
+		}
+	}
+}
+
+func TestTourFeaturesAntimeridian(t *testing.T) {
+	fc := TourFeatures([]Stop{
+		stop(1, "tokyo-japan", 35.68, 139.69, 1),
+		stop(2, "honolulu-usa", 21.31, -157.86, 4),
+	})
+	route := fc.Features[len(fc.Features)-1]
+	if route.Geometry.Type != "MultiLineString" {
+		t.Fatalf("route type = %s, want MultiLineString", route.Geometry.Type)
+	}
+	lines := route.Geometry.Coordinates.([][][]float64)
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines, want 2", len(lines))
+	}
+	for _, line := range lines {
+		for _, p := range line {
+			if p[0] < -180 || p[0] > 180 || p[1] < -90 || p[1] > 90 {
+				t.Fatalf("point %v out of range", p)
+			}
+		}
+	}
+	if end := lines[0][len(lines[0])-1][0]; end != 180 {
+		t.Errorf("first line ends at lon %v, want 180", end)
+	}
+	if start := lines[1][0][0]; start != -180 {
+		t.Errorf("second line starts at lon %v, want -180", start)
+	}
+}
+
+func TestWriteGeoJSON(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteGeoJSON(&buf, StopFeatures(nil)); err != nil {
+		t.Fatal(err)
+	}
+	var doc map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc["type"] != "FeatureCollection" {
+		t.Errorf("type = %v", doc["type"])
+	}
+	if features, ok := doc["features"].([]any); !ok || len(features) != 0 {
+		t.Errorf("features = %v, want an empty array", doc["features"])
+	}
+}
@@ -0,0 +1,55 @@
+package export
+
+import (
+	"encoding/xml"
+	"io"
+	"time"
+)
+
+type gpxDoc struct {
+	XMLName  xml.Name    `xml:"http://www.topografix.com/GPX/1/1 gpx"`
+	Version  string      `xml:"version,attr"`
+	Creator  string      `xml:"creator,attr"`
+	Metadata gpxMetadata `xml:"metadata"`
+	Route    gpxRoute    `xml:"rte"`
+}
+
+type gpxMetadata struct {
+	Name string `xml:"name"`
+	Time string `xml:"time"`
+}
+
+type gpxRoute struct {
+	Name   string     `xml:"name"`
+	Points []gpxPoint `xml:"rtept"`
+}
+
+// gpxPoint keeps the child elements in the order the schema requires.
+type gpxPoint struct {
+	Lat  float64 `xml:"lat,attr"`
+	Lon  float64 `xml:"lon,attr"`
+	Time string  `xml:"time"`
+	Name string  `xml:"name"`
+	Desc string  `xml:"desc,omitempty"`
+}
+
+// WriteGPX writes the stops of a tour as a GPX 1.1 route, one route point
+// per concert. stamp is the creation time recorded in the metadata.
+func WriteGPX(w io.Writer, name string, stops []Stop, stamp time.Time) error {
+	doc := gpxDoc{
+		Version:  "1.1",
+		Creator:  "groupie-tracker",
+		Metadata: gpxMetadata{Name: name, Time: stamp.UTC().Format(time.RFC3339)},
+		Route:    gpxRoute{Name: name, Points: []gpxPoint{}},
+	}
+	for _, s := range stops {
+		doc.Route.Points = append(doc.Route.Points, gpxPoint{
+			Lat:  s.Lat,
+			Lon:  s.Lon,
+			Time: s.Date.UTC().Format(time.RFC3339),
+			Name: s.Location,
+			Desc: s.ArtistName,
+		})
+	}
+	return writeXML(w, doc)
+}
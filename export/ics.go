@@ -0,0 +1,112 @@
+// Package export writes concert data in formats other applications read:
+// iCalendar feeds for calendar apps and GeoJSON for map tools.
+package export
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Event is a concert as it appears in a calendar: the local day of the
+// show. When Start and End are set, the day is written as those instants so
+// that it lands at the right time in every calendar; otherwise it is a
+// floating all-day event on Date.
+type Event struct {
+	UID        string // stable across exports so calendar apps update, not duplicate
+	Summary    string
+	Location   string
+	Date       time.Time // local calendar date; only year, month and day are used
+	Start, End time.Time // the instants the local day starts and ends, if known
+	HasGeo     bool
+	Lat, Lon   float64
+}
+
+// icsUTC is the RFC 5545 form of a UTC date-time.
+const icsUTC = "20060102T150405Z"
+
+// icsLineLimit is the longest line RFC 5545 allows, in octets, without CRLF.
+const icsLineLimit = 75
+
+// WriteICS writes events as an RFC 5545 calendar named name. stamp is used
+// as DTSTAMP for every event.
+func WriteICS(w io.Writer, name string, events []Event, stamp time.Time) error {
+	bw := bufio.NewWriter(w)
+	line := func(s string) {
+		bw.WriteString(foldLine(s))
+		bw.WriteString("\r\n")
+	}
+
+	line("BEGIN:VCALENDAR")
+	line("VERSION:2.0")
+	line("PRODID:-//Groupie Tracker//Concerts//EN")
+	line("CALSCALE:GREGORIAN")
+	line("METHOD:PUBLISH")
+	line("X-WR-CALNAME:" + escapeText(name))
+	for _, e := range events {
+		line("BEGIN:VEVENT")
+		line("UID:" + escapeText(e.UID))
+		line("DTSTAMP:" + stamp.UTC().Format(icsUTC))
+		if !e.Start.IsZero() {
+			line("DTSTART:" + e.Start.UTC().Format(icsUTC))
+			line("DTEND:" + e.End.UTC().Format(icsUTC))
+		} else {
+			y, m, d := e.Date.Date()
+			day := time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+			line("DTSTART;VALUE=DATE:" + day.Format("20060102"))
+			line("DTEND;VALUE=DATE:" + day.AddDate(0, 0, 1).Format("20060102"))
+		}
+		line("SUMMARY:" + escapeText(e.Summary))
+		if e.Location != "" {
+			line("LOCATION:" + escapeText(e.Location))
+		}
+		if e.HasGeo {
+			line(fmt.Sprintf("GEO:%.6f;%.6f", e.Lat, e.Lon))
+		}
+		line("TRANSP:TRANSPARENT")
+		line("END:VEVENT")
+	}
+	line("END:VCALENDAR")
+	return bw.Flush()
+}
+
+// textEscaper escapes the characters RFC 5545 section 3.3.11 reserves.
+var textEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	";", `\;`,
+	",", `\,`,
+	"\r\n", `\n`,
+	"\n", `\n`,
+	"\r", `\n`,
+)
+
+// escapeText escapes a TEXT value.
+func escapeText(s string) string {
+	return textEscaper.Replace(s)
+}
+
+// foldLine splits a content line into chunks of at most icsLineLimit
+// octets joined by CRLF and a space (RFC 5545 section 3.1), never breaking
+// a UTF-8 sequence.
+func foldLine(s string) string {
+	if len(s) <= icsLineLimit {
+		return s
+	}
+	var b strings.Builder
+	limit := icsLineLimit
+	for len(s) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(s[cut]) {
+			cut--
+		}
+		b.WriteString(s[:cut])
+		b.WriteString("\r\n ")
+		s = s[cut:]
+		limit = icsLineLimit - 1 // the leading space counts
+	}
+	b.WriteString(s)
+	return b.String()
+}
@@ -0,0 +1,97 @@
+package export
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+func TestWriteICS(t *testing.T) {
+	var buf bytes.Buffer
+	events := []Event{{
+		UID:      "20200301-abc-1@groupie-tracker",
+		Summary:  "Queen in Paris, France",
+		Location: "Paris, France",
+		Date:     time.Date(2020, 3, 1, 0, 0, 0, 0, time.UTC),
+		HasGeo:   true, Lat: 48.8566, Lon: 2.3522,
+	}}
+	stamp := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	if err := WriteICS(&buf, "Queen concerts", events, stamp); err != nil {
+		t.Fatalf("WriteICS returned %v", err)
+	}
+	out := buf.String()
+
+	for _, want := range []string{
+		"BEGIN:VCALENDAR\r\nVERSION:2.0\r\n",
+		"X-WR-CALNAME:Queen concerts\r\n",
+		"UID:20200301-abc-1@groupie-tracker\r\n",
+		"DTSTAMP:20240102T030405Z\r\n",
+		"DTSTART;VALUE=DATE:20200301\r\nDTEND;VALUE=DATE:20200302\r\n",
+		`SUMMARY:Queen in Paris\, France` + "\r\n",
+		`LOCATION:Paris\, France` + "\r\n",
+		"GEO:48.856600;2.352200\r\n",
+		"END:VEVENT\r\nEND:VCALENDAR\r\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output lacks %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(strings.ReplaceAll(out, "\r\n", ""), "\n") {
+		t.Error("output contains a bare LF")
+	}
+}
+
+func TestWriteICSLocalDay(t *testing.T) {
+	var buf bytes.Buffer
+	tokyo := time.FixedZone("JST", 9*60*60)
+	events := []Event{{
+		UID:     "20200301-abc-2@groupie-tracker",
+		Summary: "Queen in Tokyo, Japan",
+		Date:    time.Date(2020, 3, 1, 0, 0, 0, 0, time.UTC),
+		Start:   time.Date(2020, 3, 1, 0, 0, 0, 0, tokyo),
+		End:     time.Date(2020, 3, 2, 0, 0, 0, 0, tokyo),
+	}}
+	if err := WriteICS(&buf, "Queen concerts", events, time.Now()); err != nil {
+		t.Fatalf("WriteICS returned %v", err)
+	}
+	// midnight in Tokyo is 15:00 UTC the day before
+	if want := "DTSTART:20200229T150000Z\r\nDTEND:20200301T150000Z\r\n"; !strings.Contains(buf.String(), want) {
+		t.Errorf("output lacks %q:\n%s", want, buf.String())
+	}
+}
+
+func TestEscapeText(t *testing.T) {
+	got := escapeText("a\\b;c,d\ne")
+	if want := `a\\b\;c\,d\ne`; got != want {
+		t.Errorf("escapeText = %q, want %q", got, want)
+	}
+}
+
+func TestFoldLine(t *testing.T) {
+	long := "SUMMARY:" + strings.Repeat("é", 60) // 128 octets
+	folded := foldLine(long)
+	lines := strings.Split(folded, "\r\n")
+	if len(lines) < 2 {
+		t.Fatalf("line of %d octets was not folded", len(long))
+	}
+	for i, l := range lines {
+		if len(l) > icsLineLimit {
+			t.Errorf("line %d has %d octets", i, len(l))
+		}
+		if i > 0 && !strings.HasPrefix(l, " ") {
+			t.Errorf("continuation line %d doesn't start with a space", i)
+		}
+		if !utf8.ValidString(l) {
+			t.Errorf("line %d splits a UTF-8 sequence", i)
+		}
+	}
+	// unfolding gives the original back
+	if got := strings.ReplaceAll(folded, "\r\n ", ""); got != long {
+		t.Errorf("unfolded line = %q", got)
+	}
+	if short := "UID:1"; foldLine(short) != short {
+		t.Error("short line was changed")
+	}
+}
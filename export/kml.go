@@ -0,0 +1,84 @@
+package export
+
+import (
+	"encoding/xml"
+	"io"
+	"strconv"
+	"strings"
+)
+
+type kmlDoc struct {
+	XMLName  xml.Name    `xml:"http://www.opengis.net/kml/2.2 kml"`
+	Document kmlDocument `xml:"Document"`
+}
+
+type kmlDocument struct {
+	Name       string         `xml:"name"`
+	Placemarks []kmlPlacemark `xml:"Placemark"`
+}
+
+type kmlPlacemark struct {
+	Name        string         `xml:"name"`
+	Description string         `xml:"description,omitempty"`
+	TimeStamp   *kmlTimeStamp  `xml:"TimeStamp,omitempty"`
+	Point       *kmlPoint      `xml:"Point,omitempty"`
+	LineString  *kmlLineString `xml:"LineString,omitempty"`
+}
+
+type kmlTimeStamp struct {
+	When string `xml:"when"`
+}
+
+type kmlPoint struct {
+	Coordinates string `xml:"coordinates"`
+}
+
+type kmlLineString struct {
+	Tessellate  int    `xml:"tessellate"`
+	Coordinates string `xml:"coordinates"`
+}
+
+// WriteKML writes the stops of a tour as KML 2.2. Every stop is a placemark
+// stamped with its date, so Google Earth can play the tour back on its time
+// slider; a tessellated line placemark joins them, which Earth draws along
+// the ground.
+func WriteKML(w io.Writer, name string, stops []Stop) error {
+	doc := kmlDoc{Document: kmlDocument{Name: name}}
+	route := make([]string, 0, len(stops))
+	for _, s := range stops {
+		coord := kmlCoordinate(s)
+		doc.Document.Placemarks = append(doc.Document.Placemarks, kmlPlacemark{
+			Name:        s.Location,
+			Description: "Concert #" + strconv.Itoa(s.Order) + " of " + s.ArtistName,
+			TimeStamp:   &kmlTimeStamp{When: s.Date.Format("2006-01-02")},
+			Point:       &kmlPoint{Coordinates: coord},
+		})
+		route = append(route, coord)
+	}
+	if len(stops) > 1 {
+		doc.Document.Placemarks = append(doc.Document.Placemarks, kmlPlacemark{
+			Name:       name,
+			LineString: &kmlLineString{Tessellate: 1, Coordinates: strings.Join(route, " ")},
+		})
+	}
+	return writeXML(w, doc)
+}
+
+// kmlCoordinate formats a stop as KML's "lon,lat" tuple.
+func kmlCoordinate(s Stop) string {
+	return strconv.FormatFloat(s.Lon, 'f', -1, 64) + "," + strconv.FormatFloat(s.Lat, 'f', -1, 64)
+}
+
+// writeXML writes v as an indented XML document.
+func writeXML(w io.Writer, v any) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
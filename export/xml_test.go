@@ -0,0 +1,195 @@
+package export
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// element is a decoded XML element, enough to check a document against the
+// parts of the KML and GPX schemas the exports rely on.
+type element struct {
+	Name     xml.Name
+	Attrs    map[string]string
+	Text     string
+	Children []*element
+}
+
+func parseXML(t *testing.T, data []byte) *element {
+	t.Helper()
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	var stack []*element
+	var root *element
+	for {
+		tok, err := dec.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatalf("decoding XML: %v\n%s", err, data)
+		}
+		switch tok := tok.(type) {
+		case xml.StartElement:
+			e := &element{Name: tok.Name, Attrs: map[string]string{}}
+			for _, a := range tok.Attr {
+				e.Attrs[a.Name.Local] = a.Value
+			}
+			if len(stack) > 0 {
+				parent := stack[len(stack)-1]
+				parent.Children = append(parent.Children, e)
+			} else {
+				root = e
+			}
+			stack = append(stack, e)
+		case xml.EndElement:
+			stack = stack[:len(stack)-1]
+		case xml.CharData:
+			if len(stack) > 0 {
+				stack[len(stack)-1].Text += strings.TrimSpace(string(tok))
+			}
+		}
+	}
+	if root == nil || len(stack) != 0 {
+		t.Fatalf("not well-formed XML:\n%s", data)
+	}
+	return root
+}
+
+// validateXML checks data against the schema in testdata with xmllint, and
+// skips the test when xmllint isn't installed.
+func validateXML(t *testing.T, schema string, data []byte) {
+	t.Helper()
+	xmllint, err := exec.LookPath("xmllint")
+	if err != nil {
+		t.Skip("xmllint not installed; skipping schema validation")
+	}
+	cmd := exec.Command(xmllint, "--noout", "--nonet", "--schema", filepath.Join("testdata", schema), "-")
+	cmd.Stdin = bytes.NewReader(data)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("%s does not validate: %v\n%s\n%s", schema, err, out, data)
+	}
+}
+
+func (e *element) childNames() []string {
+	var names []string
+	for _, c := range e.Children {
+		names = append(names, c.Name.Local)
+	}
+	return names
+}
+
+func (e *element) all(name string) []*element {
+	var out []*element
+	for _, c := range e.Children {
+		if c.Name.Local == name {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+func tourStops() []Stop {
+	return []Stop{
+		stop(1, "paris-france", 48.85, 2.35, 1),
+		stop(3, "berlin-germany", 52.52, 13.4, 3),
+	}
+}
+
+func TestWriteKML(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteKML(&buf, "Queen & friends tour", tourStops()); err != nil {
+		t.Fatal(err)
+	}
+	root := parseXML(t, buf.Bytes())
+	if root.Name.Space != "http://www.opengis.net/kml/2.2" || root.Name.Local != "kml" {
+		t.Fatalf("root = %v, want the KML 2.2 kml element", root.Name)
+	}
+	docs := root.all("Document")
+	if len(docs) != 1 {
+		t.Fatalf("got %d Document elements, want 1", len(docs))
+	}
+	doc := docs[0]
+	if names := doc.all("name"); len(names) != 1 || names[0].Text != "Queen & friends tour" {
+		t.Errorf("document name = %v", doc.all("name"))
+	}
+	placemarks := doc.all("Placemark")
+	if len(placemarks) != 3 {
+		t.Fatalf("got %d placemarks, want 2 stops and a route", len(placemarks))
+	}
+
+	// Schema order within a placemark: name, description, TimeStamp, geometry.
+	want := []string{"name", "description", "TimeStamp", "Point"}
+	if got := placemarks[0].childNames(); strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("placemark children = %v, want %v", got, want)
+	}
+	if when := placemarks[1].all("TimeStamp")[0].all("when")[0].Text; when != "2020-01-03" {
+		t.Errorf("when = %q, want 2020-01-03", when)
+	}
+	if c := placemarks[0].all("Point")[0].all("coordinates")[0].Text; c != "2.35,48.85" {
+		t.Errorf("coordinates = %q, want lon,lat", c)
+	}
+	line := placemarks[2].all("LineString")
+	if len(line) != 1 {
+		t.Fatalf("route placemark has no LineString")
+	}
+	if c := line[0].all("coordinates")[0].Text; c != "2.35,48.85 13.4,52.52" {
+		t.Errorf("route coordinates = %q", c)
+	}
+	validateXML(t, "ogckml22.xsd", buf.Bytes())
+}
+
+func TestWriteKMLSingleStop(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteKML(&buf, "one", tourStops()[:1]); err != nil {
+		t.Fatal(err)
+	}
+	root := parseXML(t, buf.Bytes())
+	if n := len(root.all("Document")[0].all("Placemark")); n != 1 {
+		t.Errorf("got %d placemarks, want 1 and no route", n)
+	}
+	validateXML(t, "ogckml22.xsd", buf.Bytes())
+}
+
+func TestWriteGPX(t *testing.T) {
+	var buf bytes.Buffer
+	stamp := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	if err := WriteGPX(&buf, "Queen tour", tourStops(), stamp); err != nil {
+		t.Fatal(err)
+	}
+	root := parseXML(t, buf.Bytes())
+	if root.Name.Space != "http://www.topografix.com/GPX/1/1" || root.Name.Local != "gpx" {
+		t.Fatalf("root = %v, want the GPX 1.1 gpx element", root.Name)
+	}
+	if root.Attrs["version"] != "1.1" || root.Attrs["creator"] == "" {
+		t.Errorf("gpx attributes = %v, want version 1.1 and a creator", root.Attrs)
+	}
+	// Schema order: metadata, wpt*, rte*, trk*.
+	if got := strings.Join(root.childNames(), ","); got != "metadata,rte" {
+		t.Errorf("gpx children = %s, want metadata,rte", got)
+	}
+	if tm := root.all("metadata")[0].all("time")[0].Text; tm != "2024-01-02T03:04:05Z" {
+		t.Errorf("metadata time = %q", tm)
+	}
+	points := root.all("rte")[0].all("rtept")
+	if len(points) != 2 {
+		t.Fatalf("got %d route points, want 2", len(points))
+	}
+	p := points[1]
+	if p.Attrs["lat"] != "52.52" || p.Attrs["lon"] != "13.4" {
+		t.Errorf("rtept attributes = %v", p.Attrs)
+	}
+	// Schema order within a point: time comes before name and desc.
+	if got := strings.Join(p.childNames(), ","); got != "time,name,desc" {
+		t.Errorf("rtept children = %s, want time,name,desc", got)
+	}
+	if tm := p.all("time")[0].Text; tm != "2020-01-03T00:00:00Z" {
+		t.Errorf("rtept time = %q", tm)
+	}
+	validateXML(t, "gpx.xsd", buf.Bytes())
+}
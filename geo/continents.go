@@ -0,0 +1,50 @@
+package geo
+
+// Continent is one of the continents used to describe where a tour went.
+type Continent string
+
+const (
+	Africa       Continent = "Africa"
+	Asia         Continent = "Asia"
+	Europe       Continent = "Europe"
+	NorthAmerica Continent = "North America" // including Central America and the Caribbean
+	Oceania      Continent = "Oceania"
+	SouthAmerica Continent = "South America"
+)
+
+// continents maps every country in the table to its continent. Russia and
+// Turkey count as Europe, where the concerts in the data take place.
+var continents = map[string]Continent{
+	"AE": Asia, "AR": SouthAmerica, "AT": Europe, "AU": Oceania, "BE": Europe,
+	"BG": Europe, "BR": SouthAmerica, "BY": Europe, "CA": NorthAmerica, "CH": Europe,
+	"CL": SouthAmerica, "CN": Asia, "CO": SouthAmerica, "CR": NorthAmerica, "CW": NorthAmerica,
+	"CZ": Europe, "DE": Europe, "DK": Europe, "EC": SouthAmerica, "EE": Europe,
+	"EG": Africa, "ES": Europe, "FI": Europe, "FR": Europe, "GB": Europe,
+	"GR": Europe, "HK": Asia, "HR": Europe, "HU": Europe, "ID": Asia,
+	"IE": Europe, "IL": Asia, "IN": Asia, "IS": Europe, "IT": Europe,
+	"JP": Asia, "KR": Asia, "KZ": Asia, "LT": Europe, "LU": Europe,
+	"LV": Europe, "MX": NorthAmerica, "MY": Asia, "NC": Oceania, "NL": Europe,
+	"NO": Europe, "NZ": Oceania, "PE": SouthAmerica, "PF": Oceania, "PH": Asia,
+	"PL": Europe, "PR": NorthAmerica, "PT": Europe, "QA": Asia, "RO": Europe,
+	"RS": Europe, "RU": Europe, "SA": Asia, "SE": Europe, "SG": Asia,
+	"SI": Europe, "SK": Europe, "TH": Asia, "TR": Europe, "TW": Asia,
+	"UA": Europe, "US": NorthAmerica, "UY": SouthAmerica, "VE": SouthAmerica, "ZA": Africa,
+}
+
+// Continent returns the continent the country lies on.
+func (c Country) Continent() Continent {
+	return continents[c.Code]
+}
+
+// ContinentOf returns the continent of the country named in a
+// "City, Country" location, or "" if the country is unknown.
+func ContinentOf(location string) Continent {
+	_, countryName := SplitLocation(location)
+	if countryName == "" {
+		countryName = location
+	}
+	if c, ok := LookupCountry(countryName); ok {
+		return c.Continent()
+	}
+	return ""
+}
@@ -0,0 +1,169 @@
+package geo
+
+import "strings"
+
+// Country is an entry in the built-in country table.
+type Country struct {
+	Code    string   // ISO 3166-1 alpha-2
+	Name    string   // name as formatLocationName produces it
+	Aliases []string // other spellings seen in the data or typed by users
+	Lat     float64  // rough centroid, used when only the country is known
+	Lon     float64
+	BBox    BBox // mainland extent, used to sanity-check geocoder results
+}
+
+// BBox is a latitude/longitude bounding box. West is greater than East
+// when the box crosses the antimeridian.
+type BBox struct {
+	South, North, West, East float64
+}
+
+// Contains reports whether the point lies inside the box.
+func (b BBox) Contains(lat, lon float64) bool {
+	if lat < b.South || lat > b.North {
+		return false
+	}
+	if b.West <= b.East {
+		return lon >= b.West && lon <= b.East
+	}
+	return lon >= b.West || lon <= b.East
+}
+
+// Contains reports whether the point lies inside the country: inside one
+// of its regions if it has any, else inside its box.
+func (c Country) Contains(lat, lon float64) bool {
+	regions, ok := countryRegions[c.Code]
+	if !ok {
+		return c.BBox.Contains(lat, lon)
+	}
+	for _, r := range regions {
+		if r.Contains(lat, lon) {
+			return true
+		}
+	}
+	return false
+}
+
+// countryRegions splits countries whose box would take in too much of
+// their neighbours, or miss the parts lying apart from the mainland.
+var countryRegions = map[string][]BBox{
+	"US": {
+		{31.3, 49.0, -124.8, -94.6},  // the West and Plains, Mexico lies south of 31.3
+		{25.8, 36.5, -106.7, -93.5},  // Texas and Louisiana
+		{24.4, 41.7, -94.6, -66.9},   // the South and East below the lakes
+		{41.7, 49.4, -94.6, -82.4},   // the upper Midwest
+		{41.7, 43.3, -80.6, -76.0},   // western New York and Pennsylvania
+		{40.5, 45.0, -76.0, -69.9},   // upstate New York and New England
+		{43.0, 47.5, -71.1, -66.9},   // Maine
+		{51.2, 71.4, 172.4, -141.0},  // Alaska and the Aleutians
+		{54.6, 60.4, -141.0, -129.9}, // the Alaska panhandle
+		{18.9, 22.3, -160.3, -154.8}, // Hawaii
+	},
+}
+
+var countries = []Country{
+	{"AE", "United Arab Emirates", []string{"UAE", "Emirates"}, 23.4241, 53.8478, BBox{22.6, 26.1, 51.5, 56.4}},
+	{"AR", "Argentina", nil, -38.4161, -63.6167, BBox{-55.1, -21.8, -73.6, -53.6}},
+	{"AT", "Austria", []string{"Österreich"}, 47.5162, 14.5501, BBox{46.4, 49.0, 9.5, 17.2}},
+	{"AU", "Australia", nil, -25.2744, 133.7751, BBox{-43.7, -10.0, 112.9, 153.7}},
+	{"BE", "Belgium", []string{"België", "Belgique"}, 50.5039, 4.4699, BBox{49.5, 51.5, 2.5, 6.4}},
+	{"BG", "Bulgaria", nil, 42.7339, 25.4858, BBox{41.2, 44.2, 22.4, 28.6}},
+	{"BR", "Brazil", []string{"Brasil"}, -14.2350, -51.9253, BBox{-33.8, 5.3, -74.0, -34.7}},
+	{"BY", "Belarus", nil, 53.7098, 27.9534, BBox{51.2, 56.2, 23.2, 32.8}},
+	{"CA", "Canada", nil, 56.1304, -106.3468, BBox{41.7, 83.2, -141.0, -52.6}},
+	{"CH", "Switzerland", []string{"Schweiz", "Suisse"}, 46.8182, 8.2275, BBox{45.8, 47.8, 5.9, 10.5}},
+	{"CL", "Chile", nil, -35.6751, -71.5430, BBox{-56.0, -17.5, -75.7, -66.4}},
+	{"CN", "China", []string{"PRC"}, 35.8617, 104.1954, BBox{18.1, 53.6, 73.5, 134.8}},
+	{"CO", "Colombia", nil, 4.5709, -74.2973, BBox{-4.2, 12.5, -79.0, -66.8}},
+	{"CR", "Costa Rica", nil, 9.7489, -83.7534, BBox{8.0, 11.2, -85.9, -82.5}},
+	{"CW", "Curacao", []string{"Curaçao", "Netherlands Antilles"}, 12.1696, -68.9900, BBox{12.0, 12.4, -69.2, -68.7}},
+	{"CZ", "Czechia", []string{"Czech Republic"}, 49.8175, 15.4730, BBox{48.5, 51.1, 12.1, 18.9}},
+	{"DE", "Germany", []string{"Deutschland"}, 51.1657, 10.4515, BBox{47.3, 55.1, 5.9, 15.0}},
+	{"DK", "Denmark", []string{"Danmark"}, 56.2639, 9.5018, BBox{54.5, 57.8, 8.0, 15.2}},
+	{"EC", "Ecuador", nil, -1.8312, -78.1834, BBox{-5.0, 1.5, -81.1, -75.2}},
+	{"EE", "Estonia", nil, 58.5953, 25.0136, BBox{57.5, 59.7, 21.8, 28.2}},
+	{"EG", "Egypt", nil, 26.8206, 30.8025, BBox{22.0, 31.7, 24.7, 36.9}},
+	{"ES", "Spain", []string{"España", "Espana"}, 40.4637, -3.7492, BBox{27.6, 43.8, -18.2, 4.3}},
+	{"FI", "Finland", []string{"Suomi"}, 61.9241, 25.7482, BBox{59.8, 70.1, 20.5, 31.6}},
+	{"FR", "France", nil, 46.2276, 2.2137, BBox{41.3, 51.1, -5.2, 9.6}},
+	{"GB", "UK", []string{"United Kingdom", "Great Britain", "England", "Scotland", "Wales", "Northern Ireland"}, 55.3781, -3.4360, BBox{49.9, 60.9, -8.7, 1.8}},
+	{"GR", "Greece", []string{"Hellas"}, 39.0742, 21.8243, BBox{34.8, 41.8, 19.4, 29.6}},
+	{"HK", "Hong Kong", nil, 22.3193, 114.1694, BBox{22.1, 22.6, 113.8, 114.5}},
+	{"HR", "Croatia", nil, 45.1000, 15.2000, BBox{42.4, 46.6, 13.5, 19.5}},
+	{"HU", "Hungary", nil, 47.1625, 19.5033, BBox{45.7, 48.6, 16.1, 22.9}},
+	{"ID", "Indonesia", nil, -0.7893, 113.9213, BBox{-11.0, 6.1, 95.0, 141.0}},
+	{"IE", "Ireland", []string{"Eire"}, 53.1424, -7.6921, BBox{51.4, 55.4, -10.5, -6.0}},
+	{"IL", "Israel", nil, 31.0461, 34.8516, BBox{29.5, 33.3, 34.3, 35.9}},
+	{"IN", "India", nil, 20.5937, 78.9629, BBox{6.7, 35.5, 68.1, 97.4}},
+	{"IS", "Iceland", nil, 64.9631, -19.0208, BBox{63.3, 66.6, -24.5, -13.5}},
+	{"IT", "Italy", []string{"Italia"}, 41.8719, 12.5674, BBox{36.6, 47.1, 6.6, 18.5}},
+	{"JP", "Japan", nil, 36.2048, 138.2529, BBox{24.0, 45.6, 122.9, 145.8}},
+	{"KR", "South Korea", []string{"Korea", "Republic of Korea"}, 35.9078, 127.7669, BBox{33.1, 38.6, 124.6, 131.9}},
+	{"KZ", "Kazakhstan", []string{"Qazaqstan"}, 48.0196, 66.9237, BBox{40.5, 55.5, 46.4, 87.4}},
+	{"LT", "Lithuania", nil, 55.1694, 23.8813, BBox{53.9, 56.5, 21.0, 26.8}},
+	{"LU", "Luxembourg", nil, 49.8153, 6.1296, BBox{49.4, 50.2, 5.7, 6.5}},
+	{"LV", "Latvia", nil, 56.8796, 24.6032, BBox{55.7, 58.1, 21.0, 28.2}},
+	{"MX", "Mexico", []string{"México"}, 23.6345, -102.5528, BBox{14.5, 32.7, -118.4, -86.7}},
+	{"MY", "Malaysia", nil, 4.2105, 101.9758, BBox{0.9, 7.4, 99.6, 119.3}},
+	{"NC", "New Caledonia", []string{"Nouvelle-Calédonie"}, -20.9043, 165.6180, BBox{-22.7, -19.6, 163.6, 168.1}},
+	{"NL", "Netherlands", []string{"Holland", "The Netherlands", "Nederland"}, 52.1326, 5.2913, BBox{50.8, 53.6, 3.4, 7.2}},
+	{"NO", "Norway", []string{"Norge"}, 60.4720, 8.4689, BBox{58.0, 71.2, 4.6, 31.1}},
+	{"NZ", "New Zealand", []string{"Aotearoa"}, -40.9006, 174.8860, BBox{-47.3, -34.4, 166.4, 178.6}},
+	{"PE", "Peru", nil, -9.1900, -75.0152, BBox{-18.4, 0.0, -81.3, -68.7}},
+	{"PF", "French Polynesia", nil, -17.6797, -149.4068, BBox{-27.7, -7.9, -154.7, -134.9}},
+	{"PH", "Philippines", nil, 12.8797, 121.7740, BBox{4.6, 21.1, 116.9, 126.6}},
+	{"PL", "Poland", []string{"Polska"}, 51.9194, 19.1451, BBox{49.0, 54.9, 14.1, 24.2}},
+	{"PR", "Puerto Rico", nil, 18.2208, -66.5901, BBox{17.9, 18.5, -67.3, -65.2}},
+	{"PT", "Portugal", nil, 39.3999, -8.2245, BBox{32.4, 42.2, -31.3, -6.2}},
+	{"QA", "Qatar", nil, 25.3548, 51.1839, BBox{24.5, 26.2, 50.7, 51.7}},
+	{"RO", "Romania", nil, 45.9432, 24.9668, BBox{43.6, 48.3, 20.2, 29.7}},
+	{"RS", "Serbia", nil, 44.0165, 21.0059, BBox{42.2, 46.2, 18.8, 23.0}},
+	{"RU", "Russia", []string{"Russian Federation"}, 61.5240, 105.3188, BBox{41.2, 81.9, 19.6, -169.0}},
+	{"SA", "Saudi Arabia", nil, 23.8859, 45.0792, BBox{16.3, 32.2, 34.5, 55.7}},
+	{"SE", "Sweden", []string{"Sverige"}, 60.1282, 18.6435, BBox{55.3, 69.1, 11.0, 24.2}},
+	{"SG", "Singapore", nil, 1.3521, 103.8198, BBox{1.1, 1.5, 103.6, 104.1}},
+	{"SI", "Slovenia", nil, 46.1512, 14.9955, BBox{45.4, 46.9, 13.4, 16.6}},
+	{"SK", "Slovakia", nil, 48.6690, 19.6990, BBox{47.7, 49.6, 16.8, 22.6}},
+	{"TH", "Thailand", nil, 15.8700, 100.9925, BBox{5.6, 20.5, 97.3, 105.7}},
+	{"TR", "Turkey", []string{"Türkiye", "Turkiye"}, 38.9637, 35.2433, BBox{35.8, 42.1, 25.6, 44.8}},
+	{"TW", "Taiwan", nil, 23.6978, 120.9605, BBox{21.9, 25.3, 120.0, 122.0}},
+	{"UA", "Ukraine", nil, 48.3794, 31.1656, BBox{44.4, 52.4, 22.1, 40.2}},
+	{"US", "USA", []string{"United States", "United States of America", "US", "America"}, 39.8283, -98.5795, BBox{24.4, 49.4, -124.8, -66.9}},
+	{"UY", "Uruguay", nil, -32.5228, -55.7658, BBox{-35.0, -30.1, -58.5, -53.1}},
+	{"VE", "Venezuela", nil, 6.4238, -66.5897, BBox{0.6, 12.2, -73.4, -59.8}},
+	{"ZA", "South Africa", nil, -30.5595, 22.9375, BBox{-34.9, -22.1, 16.4, 32.9}},
+}
+
+// countryIndex maps folded names, aliases and codes to countries.
+var countryIndex = func() map[string]*Country {
+	index := make(map[string]*Country, len(countries)*3)
+	for i := range countries {
+		c := &countries[i]
+		index[fold(c.Code)] = c
+		index[fold(c.Name)] = c
+		for _, alias := range c.Aliases {
+			index[fold(alias)] = c
+		}
+	}
+	return index
+}()
+
+// LookupCountry finds a country by name, alias or ISO code.
+func LookupCountry(name string) (Country, bool) {
+	c, ok := countryIndex[fold(name)]
+	if !ok {
+		return Country{}, false
+	}
+	return *c, true
+}
+
+// CountryByCode returns the country with the given ISO 3166-1 alpha-2 code.
+func CountryByCode(code string) (Country, bool) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	for _, c := range countries {
+		if c.Code == code {
+			return c, true
+		}
+	}
+	return Country{}, false
+}
@@ -0,0 +1,89 @@
+package geo
+
+import "math"
+
+// EarthRadiusKm is the mean Earth radius used for great-circle distances.
+const EarthRadiusKm = 6371.0088
+
+// DistanceKm returns the great-circle (haversine) distance between two
+// points given in degrees.
+func DistanceKm(lat1, lon1, lat2, lon2 float64) float64 {
+	phi1, phi2 := radians(lat1), radians(lat2)
+	dPhi := phi2 - phi1
+	dLambda := radians(lon2 - lon1)
+	a := math.Sin(dPhi/2)*math.Sin(dPhi/2) + math.Cos(phi1)*math.Cos(phi2)*math.Sin(dLambda/2)*math.Sin(dLambda/2)
+	return 2 * EarthRadiusKm * math.Asin(math.Min(1, math.Sqrt(a)))
+}
+
+func radians(deg float64) float64 {
+	return deg * math.Pi / 180
+}
+
+// Point is a position in degrees.
+type Point struct {
+	Lat, Lon float64
+}
+
+// GreatCircle returns points along the shortest path from a to b, at most
+// stepKm apart and including both ends. Longitudes stay within ±180, so a
+// path crossing the antimeridian jumps from one side to the other; see
+// SplitAtAntimeridian. Antipodal points have no single shortest path and
+// are joined by a straight segment.
+func GreatCircle(a, b Point, stepKm float64) []Point {
+	d := DistanceKm(a.Lat, a.Lon, b.Lat, b.Lon)
+	// angular distance and the endpoints as unit vectors
+	delta := d / EarthRadiusKm
+	if stepKm <= 0 || d <= stepKm || math.Sin(delta) < 1e-9 {
+		return []Point{a, b}
+	}
+	n := int(math.Ceil(d / stepKm))
+	phi1, lambda1 := radians(a.Lat), radians(a.Lon)
+	phi2, lambda2 := radians(b.Lat), radians(b.Lon)
+	points := make([]Point, 0, n+1)
+	points = append(points, a)
+	for i := 1; i < n; i++ {
+		f := float64(i) / float64(n)
+		p := math.Sin((1-f)*delta) / math.Sin(delta)
+		q := math.Sin(f*delta) / math.Sin(delta)
+		x := p*math.Cos(phi1)*math.Cos(lambda1) + q*math.Cos(phi2)*math.Cos(lambda2)
+		y := p*math.Cos(phi1)*math.Sin(lambda1) + q*math.Cos(phi2)*math.Sin(lambda2)
+		z := p*math.Sin(phi1) + q*math.Sin(phi2)
+		points = append(points, Point{
+			Lat: degrees(math.Atan2(z, math.Sqrt(x*x+y*y))),
+			Lon: degrees(math.Atan2(y, x)),
+		})
+	}
+	return append(points, b)
+}
+
+// SplitAtAntimeridian cuts a path wherever it crosses longitude ±180, so
+// each part can be drawn on a flat map without a line across the world.
+// The crossing point is added to both parts.
+func SplitAtAntimeridian(path []Point) [][]Point {
+	if len(path) == 0 {
+		return nil
+	}
+	parts := [][]Point{{path[0]}}
+	for i := 1; i < len(path); i++ {
+		prev, cur := path[i-1], path[i]
+		if math.Abs(cur.Lon-prev.Lon) > 180 {
+			// unwrap cur next to prev and interpolate the latitude at the edge
+			edge, unwrapped := 180.0, cur.Lon+360
+			if prev.Lon < 0 {
+				edge, unwrapped = -180, cur.Lon-360
+			}
+			f := (edge - prev.Lon) / (unwrapped - prev.Lon)
+			lat := prev.Lat + f*(cur.Lat-prev.Lat)
+			last := len(parts) - 1
+			parts[last] = append(parts[last], Point{Lat: lat, Lon: edge})
+			parts = append(parts, []Point{{Lat: lat, Lon: -edge}})
+		}
+		last := len(parts) - 1
+		parts[last] = append(parts[last], cur)
+	}
+	return parts
+}
+
+func degrees(rad float64) float64 {
+	return rad * 180 / math.Pi
+}
@@ -0,0 +1,72 @@
+package geo
+
+import (
+	"math"
+	"testing"
+)
+
+func TestDistanceKm(t *testing.T) {
+	tests := []struct {
+		name                   string
+		lat1, lon1, lat2, lon2 float64
+		want                   float64
+	}{
+		{"same point", 48.8589, 2.3200, 48.8589, 2.3200, 0},
+		{"London to Paris", 51.5074, -0.1278, 48.8566, 2.3522, 343.6},
+		{"across the antimeridian", 0, 179.5, 0, -179.5, 111.2},
+		{"pole to pole", 90, 0, -90, 0, 20015.1},
+	}
+	for _, tt := range tests {
+		if got := DistanceKm(tt.lat1, tt.lon1, tt.lat2, tt.lon2); math.Abs(got-tt.want) > 0.5 {
+			t.Errorf("%s: DistanceKm = %.1f, want %.1f", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestGreatCircle(t *testing.T) {
+	london, newYork := Point{51.5074, -0.1278}, Point{40.7128, -74.0060}
+	path := GreatCircle(london, newYork, 100)
+	if len(path) < 50 || path[0] != london || path[len(path)-1] != newYork {
+		t.Fatalf("path has %d points from %v to %v", len(path), path[0], path[len(path)-1])
+	}
+	for i := 1; i < len(path); i++ {
+		if d := DistanceKm(path[i-1].Lat, path[i-1].Lon, path[i].Lat, path[i].Lon); d > 100.5 {
+			t.Errorf("step %d is %.1f km", i, d)
+		}
+	}
+	// the great circle bends north of both cities
+	mid := path[len(path)/2]
+	if mid.Lat < 52 {
+		t.Errorf("midpoint %v is not north of London", mid)
+	}
+	if same := GreatCircle(london, london, 100); len(same) != 2 {
+		t.Errorf("path between equal points has %d points", len(same))
+	}
+	// antipodes have no single great circle between them
+	antipode := Point{-51.5074, 179.8722}
+	if path := GreatCircle(london, antipode, 100); len(path) != 2 || path[0] != london || path[1] != antipode {
+		t.Errorf("path to the antipode = %v, want a straight segment", path)
+	}
+}
+
+func TestSplitAtAntimeridian(t *testing.T) {
+	tokyo, honolulu := Point{35.6762, 139.6503}, Point{21.3069, -157.8583}
+	parts := SplitAtAntimeridian(GreatCircle(tokyo, honolulu, 200))
+	if len(parts) != 2 {
+		t.Fatalf("got %d parts, want 2", len(parts))
+	}
+	end, start := parts[0][len(parts[0])-1], parts[1][0]
+	if end.Lon != 180 || start.Lon != -180 || end.Lat != start.Lat {
+		t.Errorf("parts meet at %v and %v", end, start)
+	}
+	for _, part := range parts {
+		for i := 1; i < len(part); i++ {
+			if math.Abs(part[i].Lon-part[i-1].Lon) > 180 {
+				t.Errorf("part still jumps across the map at %v", part[i])
+			}
+		}
+	}
+	if parts := SplitAtAntimeridian([]Point{{0, 0}, {10, 10}}); len(parts) != 1 {
+		t.Errorf("path away from the antimeridian was split into %d parts", len(parts))
+	}
+}
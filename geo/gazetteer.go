@@ -0,0 +1,220 @@
+// Package geo holds offline geographic data and helpers: a small embedded
+// gazetteer of cities and regions, the country table it resolves against,
+// continents, and a coarse timezone lookup.
+package geo
+
+import (
+	"bufio"
+	_ "embed"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"groupie-tracker/textfold"
+)
+
+// PlaceKind tells how precise a resolved place is.
+type PlaceKind string
+
+const (
+	KindCity    PlaceKind = "city"
+	KindState   PlaceKind = "state"
+	KindCountry PlaceKind = "country"
+)
+
+// Place is a gazetteer entry.
+type Place struct {
+	Name       string
+	Country    string // ISO 3166-1 alpha-2
+	Kind       PlaceKind
+	Lat        float64
+	Lon        float64
+	Population int
+}
+
+//go:embed data/cities.tsv
+var citiesTSV string
+
+// gazetteer maps folded names (including alternates) to every place
+// carrying that name.
+var gazetteer = parseGazetteer(citiesTSV)
+
+// parseGazetteer reads the tab separated dataset. Malformed lines are
+// skipped; the data is compiled in, so a bad line shows up in tests.
+func parseGazetteer(data string) map[string][]Place {
+	places := make(map[string][]Place)
+	scanner := bufio.NewScanner(strings.NewReader(data))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		f := strings.Split(line, "\t")
+		if len(f) != 7 {
+			continue
+		}
+		lat, errLat := strconv.ParseFloat(f[4], 64)
+		lon, errLon := strconv.ParseFloat(f[5], 64)
+		pop, errPop := strconv.Atoi(f[6])
+		if errLat != nil || errLon != nil || errPop != nil {
+			continue
+		}
+		p := Place{Name: f[0], Country: f[2], Kind: PlaceKind(f[3]), Lat: lat, Lon: lon, Population: pop}
+		names := []string{f[0]}
+		if f[1] != "" {
+			names = append(names, strings.Split(f[1], "|")...)
+		}
+		for _, name := range names {
+			key := fold(name)
+			places[key] = append(places[key], p)
+		}
+	}
+	return places
+}
+
+// Resolve finds the place named by a "City, Country" string as produced by
+// formatLocationName. Candidates are narrowed to the named country when it
+// is known, then chosen by exact name, then (within a known country) by the
+// smallest edit distance; ties go to cities over regions and then to the
+// larger population. If only the country can be identified, its centroid is
+// returned with KindCountry.
+func Resolve(location string) (Place, bool) {
+	name, countryName := SplitLocation(location)
+	country, hasCountry := LookupCountry(countryName)
+	key := fold(name)
+
+	inCountry := func(p Place) bool {
+		return !hasCountry || p.Country == country.Code
+	}
+
+	// exact name or alternate name
+	var best Place
+	found := false
+	for _, p := range gazetteer[key] {
+		if inCountry(p) && (!found || better(p, best)) {
+			best, found = p, true
+		}
+	}
+	if found {
+		return best, true
+	}
+
+	// fuzzy match for typos and spelling variants, only inside a known
+	// country: across the whole world too many small towns are one typo away
+	if !hasCountry {
+		// a bare country name such as "Hong Kong"
+		if c, ok := LookupCountry(name); ok && countryName == "" {
+			return countryPlace(c), true
+		}
+		return Place{}, false
+	}
+	maxDist := maxEditDistance(key)
+	bestDist := maxDist + 1
+	for candidate, places := range gazetteer {
+		d := editDistance(key, candidate, maxDist)
+		if d > maxDist || d > bestDist {
+			continue
+		}
+		for _, p := range places {
+			if !inCountry(p) {
+				continue
+			}
+			if d < bestDist || better(p, best) {
+				best, bestDist, found = p, d, true
+			}
+		}
+	}
+	if found {
+		return best, true
+	}
+	return countryPlace(country), true
+}
+
+// SplitLocation splits "City, Country" at the last comma. Without a comma
+// the whole string is the name and the country is empty.
+func SplitLocation(location string) (name, country string) {
+	if idx := strings.LastIndex(location, ","); idx != -1 {
+		return strings.TrimSpace(location[:idx]), strings.TrimSpace(location[idx+1:])
+	}
+	return strings.TrimSpace(location), ""
+}
+
+func countryPlace(c Country) Place {
+	return Place{Name: c.Name, Country: c.Code, Kind: KindCountry, Lat: c.Lat, Lon: c.Lon}
+}
+
+// better reports whether a should win over b when both match equally well.
+func better(a, b Place) bool {
+	if (a.Kind == KindCity) != (b.Kind == KindCity) {
+		return a.Kind == KindCity
+	}
+	return a.Population > b.Population
+}
+
+// maxEditDistance allows more typos in longer names.
+func maxEditDistance(s string) int {
+	switch n := len([]rune(s)); {
+	case n <= 3:
+		return 0
+	case n <= 7:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// editDistance returns the optimal string alignment distance (Levenshtein
+// plus adjacent transpositions) between a and b. It gives up early and
+// returns max+1 once the distance is known to exceed max.
+func editDistance(a, b string, max int) int {
+	ra, rb := []rune(a), []rune(b)
+	if diff := len(ra) - len(rb); diff > max || -diff > max {
+		return max + 1
+	}
+	prev2 := make([]int, len(rb)+1)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		rowMin := cur[0]
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			d := prev[j] + 1 // deletion
+			if v := cur[j-1] + 1; v < d {
+				d = v // insertion
+			}
+			if v := prev[j-1] + cost; v < d {
+				d = v // substitution
+			}
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				if v := prev2[j-2] + 1; v < d {
+					d = v // transposition
+				}
+			}
+			cur[j] = d
+			if d < rowMin {
+				rowMin = d
+			}
+		}
+		if rowMin > max {
+			return max + 1
+		}
+		prev2, prev, cur = prev, cur, prev2
+	}
+	return prev[len(rb)]
+}
+
+// fold lowercases s, strips accents and reduces punctuation to single
+// spaces, so "St. Gallen", "st-gallen" and "St Gallen" compare equal.
+func fold(s string) string {
+	s = textfold.String(s)
+	return strings.Join(strings.FieldsFunc(s, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}), " ")
+}
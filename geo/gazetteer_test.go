@@ -0,0 +1,170 @@
+package geo
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGazetteerDataParses(t *testing.T) {
+	lines := 0
+	for _, line := range strings.Split(citiesTSV, "\n") {
+		if line != "" && !strings.HasPrefix(line, "#") {
+			lines++
+		}
+	}
+	parsed := make(map[Place]bool)
+	for _, places := range gazetteer {
+		for _, p := range places {
+			parsed[p] = true
+			if _, ok := CountryByCode(p.Country); !ok {
+				t.Errorf("%s has unknown country %q", p.Name, p.Country)
+			}
+		}
+	}
+	if len(parsed) != lines {
+		t.Errorf("parsed %d places from %d data lines", len(parsed), lines)
+	}
+}
+
+func TestResolve(t *testing.T) {
+	tests := []struct {
+		location string
+		name     string
+		country  string
+		kind     PlaceKind
+	}{
+		{"Paris, France", "Paris", "FR", KindCity},
+		{"Birmingham, UK", "Birmingham", "GB", KindCity},
+		{"Birmingham, USA", "Birmingham", "US", KindCity},
+		{"Victoria, Australia", "Victoria", "AU", KindState},
+		{"Victoria, Canada", "Victoria", "CA", KindCity},
+		{"Alabama, USA", "Alabama", "US", KindState},
+		{"New York, USA", "New York", "US", KindCity},
+		{"Zürich, Switzerland", "Zurich", "CH", KindCity},
+		{"St. Gallen, Switzerland", "St Gallen", "CH", KindCity},
+		{"Monchengladbach, Germany", "Monchengladbach", "DE", KindCity},
+		{"Munchen, Germany", "Munich", "DE", KindCity},      // fuzzy alternate name
+		{"Gotheburg, Sweden", "Gothenburg", "SE", KindCity}, // typo
+		{"Atlantis, Greece", "Greece", "GR", KindCountry},   // unknown city
+		{"Willemstad, Netherlands Antilles", "Willemstad", "CW", KindCity},
+	}
+	for _, tt := range tests {
+		got, ok := Resolve(tt.location)
+		if !ok {
+			t.Errorf("Resolve(%q) found nothing", tt.location)
+			continue
+		}
+		if got.Name != tt.name || got.Country != tt.country || got.Kind != tt.kind {
+			t.Errorf("Resolve(%q) = %s/%s/%s, want %s/%s/%s", tt.location,
+				got.Name, got.Country, got.Kind, tt.name, tt.country, tt.kind)
+		}
+	}
+}
+
+func TestResolveUnknown(t *testing.T) {
+	if p, ok := Resolve("Nowhere, Neverland"); ok {
+		t.Errorf("Resolve found %+v for an unknown place", p)
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"paris", "paris", 0},
+		{"pariss", "paris", 1},
+		{"gotheburg", "gothenburg", 1},
+		{"beatels", "beatles", 1}, // transposition
+		{"london", "berlin", 3},   // capped at max+1
+	}
+	for _, tt := range tests {
+		if got := editDistance(tt.a, tt.b, 2); got != tt.want {
+			t.Errorf("editDistance(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestLookupCountry(t *testing.T) {
+	for _, name := range []string{"USA", "United States", "us"} {
+		if c, ok := LookupCountry(name); !ok || c.Code != "US" {
+			t.Errorf("LookupCountry(%q) = %+v, %v", name, c, ok)
+		}
+	}
+	if _, ok := LookupCountry("Neverland"); ok {
+		t.Error("LookupCountry(Neverland) should fail")
+	}
+}
+
+func TestGazetteerPlacesInsideTheirCountry(t *testing.T) {
+	for _, places := range gazetteer {
+		for _, p := range places {
+			c, _ := CountryByCode(p.Country)
+			if !c.Contains(p.Lat, p.Lon) {
+				t.Errorf("%s (%.4f, %.4f) lies outside %s", p.Name, p.Lat, p.Lon, c.Name)
+			}
+		}
+	}
+}
+
+func TestBBoxContainsAcrossAntimeridian(t *testing.T) {
+	b := BBox{South: 40, North: 80, West: 170, East: -170}
+	if !b.Contains(60, 175) || !b.Contains(60, -175) {
+		t.Error("points on either side of the antimeridian should be inside")
+	}
+	if b.Contains(60, 0) {
+		t.Error("a point at longitude 0 should be outside")
+	}
+}
+
+func TestUSRegions(t *testing.T) {
+	us, _ := CountryByCode("US")
+	inside := map[string][2]float64{
+		"New York":    {40.71, -74.01},
+		"Seattle":     {47.61, -122.33},
+		"Miami":       {25.76, -80.19},
+		"Houston":     {29.76, -95.37},
+		"Detroit":     {42.33, -83.05},
+		"Buffalo":     {42.89, -78.88},
+		"Boston":      {42.36, -71.06},
+		"Anchorage":   {61.22, -149.90},
+		"Juneau":      {58.30, -134.42},
+		"Honolulu":    {21.31, -157.86},
+		"Adak":        {51.88, -176.66},
+		"San Antonio": {29.42, -98.49},
+	}
+	for name, p := range inside {
+		if !us.Contains(p[0], p[1]) {
+			t.Errorf("%s should be inside the USA", name)
+		}
+	}
+	outside := map[string][2]float64{
+		"Toronto":    {43.65, -79.38},
+		"Montreal":   {45.50, -73.57},
+		"Vancouver":  {49.28, -123.12},
+		"Calgary":    {51.05, -114.07},
+		"Winnipeg":   {49.90, -97.14},
+		"Monterrey":  {25.69, -100.32},
+		"Hermosillo": {29.07, -110.96},
+		"Whitehorse": {60.72, -135.06},
+	}
+	for name, p := range outside {
+		if us.Contains(p[0], p[1]) {
+			t.Errorf("%s should be outside the USA", name)
+		}
+	}
+}
+
+func TestEveryCountryHasAContinent(t *testing.T) {
+	for _, c := range countries {
+		if c.Continent() == "" {
+			t.Errorf("%s (%s) has no continent", c.Name, c.Code)
+		}
+	}
+	if got := ContinentOf("Sao Paulo, Brazil"); got != SouthAmerica {
+		t.Errorf("ContinentOf(Sao Paulo, Brazil) = %q", got)
+	}
+	if got := ContinentOf("Atlantis, Nowhere"); got != "" {
+		t.Errorf("ContinentOf(Atlantis, Nowhere) = %q, want none", got)
+	}
+}
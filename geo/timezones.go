@@ -0,0 +1,162 @@
+package geo
+
+import (
+	"bufio"
+	_ "embed"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	_ "time/tzdata" // zones must load on hosts without a system database
+)
+
+// zoneArea is the part of a country in one timezone. An area without a
+// boundary is the rest of the country.
+type zoneArea struct {
+	Country  string
+	Zone     string
+	Boundary []Point
+}
+
+//go:embed data/timezones.tsv
+var timezonesTSV string
+
+// zoneAreas are grouped by country, in the order they are tried. Every
+// country in the table has at least one.
+var zoneAreas = parseZoneAreas(timezonesTSV)
+
+func parseZoneAreas(data string) map[string][]zoneArea {
+	areas := make(map[string][]zoneArea)
+	scanner := bufio.NewScanner(strings.NewReader(data))
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		f := strings.Split(line, "\t")
+		if len(f) < 2 || len(f) > 3 {
+			continue
+		}
+		area := zoneArea{Country: f[0], Zone: f[1]}
+		if len(f) == 3 {
+			boundary, ok := parseBoundary(f[2])
+			if !ok {
+				continue
+			}
+			area.Boundary = boundary
+		}
+		areas[f[0]] = append(areas[f[0]], area)
+	}
+	return areas
+}
+
+// parseBoundary reads space-separated "lat,lon" vertices.
+func parseBoundary(s string) ([]Point, bool) {
+	var boundary []Point
+	for _, vertex := range strings.Fields(s) {
+		latText, lonText, ok := strings.Cut(vertex, ",")
+		if !ok {
+			return nil, false
+		}
+		lat, errLat := strconv.ParseFloat(latText, 64)
+		lon, errLon := strconv.ParseFloat(lonText, 64)
+		if errLat != nil || errLon != nil {
+			return nil, false
+		}
+		boundary = append(boundary, Point{lat, lon})
+	}
+	return boundary, len(boundary) >= 3
+}
+
+// TimezoneAt returns the IANA zone of a point in the given country: that of
+// the first of the country's areas whose boundary holds the point, else of
+// the rest of the country, else of the closest area, for points just off a
+// simplified coast. For an unknown country the country the point lies in
+// is used; with no data at all the answer is "UTC".
+func TimezoneAt(countryCode string, lat, lon float64) string {
+	areas := zoneAreas[strings.ToUpper(countryCode)]
+	if len(areas) == 0 {
+		areas = zoneAreas[countryAt(lat, lon).Code]
+	}
+	best, bestDistance := "UTC", -1.0
+	for _, a := range areas {
+		if a.Boundary == nil {
+			return a.Zone
+		}
+		if inPolygon(a.Boundary, lat, lon) {
+			return a.Zone
+		}
+		if d := polygonDistance(a.Boundary, lat, lon); bestDistance < 0 || d < bestDistance {
+			best, bestDistance = a.Zone, d
+		}
+	}
+	return best
+}
+
+// countryAt returns the country whose regions hold the point, the one with
+// the nearest centroid if several or none do.
+func countryAt(lat, lon float64) Country {
+	var best Country
+	bestKm, bestInside := -1.0, false
+	for _, c := range countries {
+		inside := c.Contains(lat, lon)
+		if bestInside && !inside {
+			continue
+		}
+		km := DistanceKm(lat, lon, c.Lat, c.Lon)
+		if bestKm < 0 || (inside && !bestInside) || km < bestKm {
+			best, bestKm, bestInside = c, km, inside
+		}
+	}
+	return best
+}
+
+// inPolygon reports whether a point lies inside polygon, counting the
+// crossings of a ray running east from it.
+func inPolygon(polygon []Point, lat, lon float64) bool {
+	inside := false
+	for i, j := 0, len(polygon)-1; i < len(polygon); j, i = i, i+1 {
+		a, b := polygon[i], polygon[j]
+		if (a.Lat > lat) != (b.Lat > lat) && lon < a.Lon+(lat-a.Lat)*(b.Lon-a.Lon)/(b.Lat-a.Lat) {
+			inside = !inside
+		}
+	}
+	return inside
+}
+
+// polygonDistance returns how far a point lies from the edges of polygon,
+// in degrees of latitude. Longitudes are scaled to the point's latitude,
+// which is close enough to rank nearby areas.
+func polygonDistance(polygon []Point, lat, lon float64) float64 {
+	scale := math.Cos(radians(lat))
+	best := math.Inf(1)
+	for i, j := 0, len(polygon)-1; i < len(polygon); j, i = i, i+1 {
+		ax, ay := (polygon[j].Lon-lon)*scale, polygon[j].Lat-lat
+		bx, by := (polygon[i].Lon-lon)*scale, polygon[i].Lat-lat
+		// closest point of the edge to the origin
+		dx, dy := bx-ax, by-ay
+		t := 0.0
+		if length := dx*dx + dy*dy; length > 0 {
+			t = math.Max(0, math.Min(1, -(ax*dx+ay*dy)/length))
+		}
+		best = math.Min(best, math.Hypot(ax+t*dx, ay+t*dy))
+	}
+	return best
+}
+
+// LocationTimezone returns the zone of a "City, Country" location at the
+// given coordinates.
+func LocationTimezone(location string, lat, lon float64) *time.Location {
+	code := ""
+	if _, countryName := SplitLocation(location); countryName != "" {
+		if c, ok := LookupCountry(countryName); ok {
+			code = c.Code
+		}
+	}
+	zone, err := time.LoadLocation(TimezoneAt(code, lat, lon))
+	if err != nil {
+		return time.UTC
+	}
+	return zone
+}
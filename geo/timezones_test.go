@@ -0,0 +1,91 @@
+package geo
+
+import (
+	"testing"
+	"time"
+)
+
+func TestZoneAreasLoad(t *testing.T) {
+	for code, areas := range zoneAreas {
+		rest := 0
+		for i, a := range areas {
+			if _, err := time.LoadLocation(a.Zone); err != nil {
+				t.Errorf("%s: zone %q does not load: %v", code, a.Zone, err)
+			}
+			if a.Boundary == nil {
+				rest++
+				if i != len(areas)-1 {
+					t.Errorf("%s: the rest of the country (%s) comes before other areas", code, a.Zone)
+				}
+			}
+		}
+		if rest > 1 {
+			t.Errorf("%s: %d areas without a boundary", code, rest)
+		}
+	}
+	for _, c := range countries {
+		if len(zoneAreas[c.Code]) == 0 {
+			t.Errorf("%s (%s) has no timezone", c.Name, c.Code)
+		}
+	}
+}
+
+func TestTimezoneAt(t *testing.T) {
+	tests := []struct {
+		country  string
+		lat, lon float64
+		want     string
+	}{
+		{"FR", 43.30, 5.37, "Europe/Paris"},           // Marseille
+		{"US", 42.89, -78.88, "America/New_York"},     // Buffalo
+		{"US", 30.27, -97.74, "America/Chicago"},      // Austin
+		{"US", 32.72, -117.16, "America/Los_Angeles"}, // San Diego
+		{"AU", -31.95, 115.86, "Australia/Perth"},     // Perth
+		{"BR", -25.43, -49.27, "America/Sao_Paulo"},   // Curitiba
+		{"ES", 28.47, -16.25, "Atlantic/Canary"},      // Santa Cruz de Tenerife
+		// near zone boundaries
+		{"US", 35.96, -83.92, "America/New_York"},             // Knoxville, not Nashville's zone
+		{"US", 36.16, -86.78, "America/Chicago"},              // Nashville
+		{"US", 37.97, -100.87, "America/Chicago"},             // Garden City, Kansas
+		{"US", 39.35, -101.71, "America/Denver"},              // Goodland, Kansas
+		{"US", 30.42, -87.22, "America/Chicago"},              // Pensacola
+		{"US", 30.44, -84.28, "America/New_York"},             // Tallahassee
+		{"US", 31.76, -106.49, "America/Denver"},              // El Paso
+		{"US", 43.62, -116.20, "America/Denver"},              // Boise
+		{"US", 47.68, -116.78, "America/Los_Angeles"},         // Coeur d'Alene
+		{"US", 41.68, -86.25, "America/Indiana/Indianapolis"}, // South Bend
+		{"US", 41.59, -87.35, "America/Chicago"},              // Gary
+		{"US", 46.55, -87.40, "America/Detroit"},              // Marquette
+		{"US", 33.45, -112.07, "America/Phoenix"},             // Phoenix
+		{"CA", 48.38, -89.25, "America/Toronto"},              // Thunder Bay, not Winnipeg's
+		{"CA", 49.77, -94.49, "America/Winnipeg"},             // Kenora
+		{"CA", 60.72, -135.06, "America/Whitehorse"},          // Whitehorse
+		{"KZ", 51.23, 51.37, "Asia/Oral"},                     // Oral, western Kazakhstan
+		{"KZ", 47.10, 51.92, "Asia/Atyrau"},                   // Atyrau
+		{"KZ", 43.24, 76.89, "Asia/Almaty"},                   // Almaty
+		{"AU", -31.95, 141.45, "Australia/Adelaide"},          // Broken Hill, New South Wales
+		{"AU", -42.88, 147.33, "Australia/Hobart"},            // Hobart
+		{"MX", 20.63, -87.08, "America/Cancun"},               // Playa del Carmen
+		{"MX", 25.69, -100.32, "America/Monterrey"},           // Monterrey
+		{"ID", -8.65, 115.22, "Asia/Makassar"},                // Denpasar
+		{"ID", -7.80, 110.36, "Asia/Jakarta"},                 // Yogyakarta
+		{"BR", -19.92, -43.94, "America/Sao_Paulo"},           // Belo Horizonte
+		{"BR", -20.45, -54.62, "America/Campo_Grande"},        // Campo Grande
+		{"RU", 55.03, 82.92, "Asia/Novosibirsk"},              // Novosibirsk
+		{"RU", 56.84, 60.61, "Asia/Yekaterinburg"},            // Yekaterinburg
+		{"RU", 55.79, 49.12, "Europe/Moscow"},                 // Kazan
+		{"", 52.37, 4.90, "Europe/Amsterdam"},                 // unknown country, the one it lies in
+		{"ZZ", 35.68, 139.65, "Asia/Tokyo"},                   // unknown code
+	}
+	for _, tt := range tests {
+		if got := TimezoneAt(tt.country, tt.lat, tt.lon); got != tt.want {
+			t.Errorf("TimezoneAt(%q, %v, %v) = %q, want %q", tt.country, tt.lat, tt.lon, got, tt.want)
+		}
+	}
+}
+
+func TestLocationTimezone(t *testing.T) {
+	if got := LocationTimezone("Seattle, USA", 47.61, -122.33).String(); got != "America/Los_Angeles" {
+		t.Errorf("LocationTimezone(Seattle, USA) = %q", got)
+	}
+}
@@ -0,0 +1,179 @@
+package handlers
+
+import (
+	"context"
+	"crypto/subtle"
+	"groupie-tracker/models"
+	"groupie-tracker/services"
+	"html/template"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+)
+
+// Admin pages are only served when both ADMIN_USER and ADMIN_PASSWORD are set.
+var (
+	adminUser     = os.Getenv("ADMIN_USER")
+	adminPassword = os.Getenv("ADMIN_PASSWORD")
+
+	admin_report_tmpl, admin_geocode_tmpl, admin_anomalies_tmpl *template.Template
+)
+
+// init parses the admin templates, as the init of handlers.go does the others.
+func init() {
+	admin_report_tmpl = template.Must(template.ParseFiles("templates/admin_report.html"))
+	admin_geocode_tmpl = template.Must(template.ParseFiles("templates/admin_geocode.html"))
+	admin_anomalies_tmpl = template.Must(template.ParseFiles("templates/admin_anomalies.html"))
+}
+
+// requireAdmin wraps an admin handler with HTTP basic auth. Without
+// configured credentials the admin pages don't exist at all. Form posts must
+// come from this site so a logged-in browser can't be made to submit them.
+func requireAdmin(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if adminUser == "" || adminPassword == "" {
+			HandleErrors(w, http.StatusNotFound, http.StatusText(http.StatusNotFound), "Please check the resource URL and try again.")
+			return
+		}
+		user, password, ok := r.BasicAuth()
+		userOK := subtle.ConstantTimeCompare([]byte(user), []byte(adminUser)) == 1
+		passwordOK := subtle.ConstantTimeCompare([]byte(password), []byte(adminPassword)) == 1
+		if !ok || !userOK || !passwordOK {
+			w.Header().Set("WWW-Authenticate", `Basic realm="Groupie Tracker admin", charset="UTF-8"`)
+			HandleErrors(w, http.StatusUnauthorized, http.StatusText(http.StatusUnauthorized), "Admin credentials are required for this page.")
+			return
+		}
+		if r.Method == http.MethodPost && !sameOrigin(r) {
+			HandleErrors(w, http.StatusForbidden, http.StatusText(http.StatusForbidden), "This form must be submitted from the admin page.")
+			return
+		}
+		next(w, r)
+	}
+}
+
+// sameOrigin reports whether the Origin (or, failing that, Referer) header
+// of r names the host r was sent to.
+func sameOrigin(r *http.Request) bool {
+	source := r.Header.Get("Origin")
+	if source == "" {
+		source = r.Header.Get("Referer")
+	}
+	u, err := url.Parse(source)
+	return err == nil && u.Host != "" && u.Host == r.Host
+}
+
+// GeocodeReportHandler lists locations whose coordinates look wrong and lets
+// an admin pick one of the alternatives.
+var GeocodeReportHandler = requireAdmin(func(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/admin/geocode/report" {
+		HandleErrors(w, http.StatusNotFound, http.StatusText(http.StatusNotFound), "Please check the resource URL and try again.")
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		w.Header().Set("Cache-Control", "no-store")
+		if err := admin_report_tmpl.Execute(w, services.GeocodeReport()); err != nil {
+			HandleErrors(w, http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError), "The server was unable to complete your request. Please try again later")
+		}
+	case http.MethodPost:
+		index, err := strconv.Atoi(r.FormValue("candidate"))
+		if err != nil {
+			HandleErrors(w, http.StatusBadRequest, http.StatusText(http.StatusBadRequest), "Please choose one of the listed candidates.")
+			return
+		}
+		if err := services.ChooseCandidate(r.FormValue("location"), index); err != nil {
+			HandleErrors(w, http.StatusBadRequest, http.StatusText(http.StatusBadRequest), err.Error())
+			return
+		}
+		http.Redirect(w, r, "/admin/geocode/report", http.StatusSeeOther)
+	default:
+		HandleErrors(w, http.StatusMethodNotAllowed, http.StatusText(http.StatusMethodNotAllowed), "This request method is not supported for the requested resource. Use GET or POST request instead.")
+	}
+})
+
+// GeocodeJobHandler starts or cancels the background geocoding job.
+var GeocodeJobHandler = requireAdmin(func(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		HandleErrors(w, http.StatusMethodNotAllowed, http.StatusText(http.StatusMethodNotAllowed), "This request method is not supported for the requested resource. Use POST request instead.")
+		return
+	}
+	switch r.FormValue("action") {
+	case "start":
+		services.StartGeocodeJob(context.Background())
+	case "cancel":
+		services.CancelGeocodeJob()
+	default:
+		HandleErrors(w, http.StatusBadRequest, http.StatusText(http.StatusBadRequest), "Unknown action, use start or cancel.")
+		return
+	}
+	http.Redirect(w, r, "/admin/geocode/report", http.StatusSeeOther)
+})
+
+// GeocodeAdminHandler lists every location with its coordinates and lets an
+// admin correct, pin or unpin them.
+var GeocodeAdminHandler = requireAdmin(func(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/admin/geocode" {
+		HandleErrors(w, http.StatusNotFound, http.StatusText(http.StatusNotFound), "Please check the resource URL and try again.")
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		query := r.URL.Query().Get("q")
+		data := struct {
+			Query   string
+			Entries []models.GeocodeEntry
+		}{
+			Query:   query,
+			Entries: services.GeocodeEntries(query),
+		}
+		w.Header().Set("Cache-Control", "no-store")
+		if err := admin_geocode_tmpl.Execute(w, data); err != nil {
+			HandleErrors(w, http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError), "The server was unable to complete your request. Please try again later")
+		}
+	case http.MethodPost:
+		loc := r.FormValue("location")
+		var err error
+		switch r.FormValue("action") {
+		case "set":
+			lat, errLat := strconv.ParseFloat(r.FormValue("lat"), 64)
+			lon, errLon := strconv.ParseFloat(r.FormValue("lon"), 64)
+			if errLat != nil || errLon != nil {
+				HandleErrors(w, http.StatusBadRequest, http.StatusText(http.StatusBadRequest), "Latitude and longitude must be numbers.")
+				return
+			}
+			err = services.SetCoordinates(loc, lat, lon, r.FormValue("pin") != "")
+		case "pin":
+			err = services.SetPinned(loc, true)
+		case "unpin":
+			err = services.SetPinned(loc, false)
+		default:
+			HandleErrors(w, http.StatusBadRequest, http.StatusText(http.StatusBadRequest), "Unknown action, use set, pin or unpin.")
+			return
+		}
+		if err != nil {
+			HandleErrors(w, http.StatusBadRequest, http.StatusText(http.StatusBadRequest), err.Error())
+			return
+		}
+		// back to the same filtered list
+		http.Redirect(w, r, "/admin/geocode?q="+url.QueryEscape(r.FormValue("q")), http.StatusSeeOther)
+	default:
+		HandleErrors(w, http.StatusMethodNotAllowed, http.StatusText(http.StatusMethodNotAllowed), "This request method is not supported for the requested resource. Use GET or POST request instead.")
+	}
+})
+
+// AnomaliesHandler lists concerts whose dates imply impossible travel.
+var AnomaliesHandler = requireAdmin(func(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/admin/anomalies" {
+		HandleErrors(w, http.StatusNotFound, http.StatusText(http.StatusNotFound), "Please check the resource URL and try again.")
+		return
+	}
+	if r.Method != http.MethodGet {
+		HandleErrors(w, http.StatusMethodNotAllowed, http.StatusText(http.StatusMethodNotAllowed), "This request method is not supported for the requested resource. Use GET request instead.")
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	if err := admin_anomalies_tmpl.Execute(w, services.AllTravelAnomalies()); err != nil {
+		HandleErrors(w, http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError), "The server was unable to complete your request. Please try again later")
+	}
+})
@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"groupie-tracker/api"
+	"groupie-tracker/export"
+	"groupie-tracker/models"
+	"groupie-tracker/services"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// artistStops lists the mapped concerts of an artist in chronological
+// order, with coordinates from the geocode cache and the tour each belongs
+// to. Order counts every concert, so unmapped ones leave a gap.
+func artistStops(artist *models.Artists, relations *models.Relations, dates *models.Dates) []export.Stop {
+	concerts := services.Concerts(relations)
+	coords := services.CachedCoordinates(relations.SortedLocations)
+	tourOf := make([]int, 0, len(concerts))
+	for _, tour := range services.SegmentTours(concerts, dates) {
+		for _, leg := range tour.Legs {
+			for range leg.Concerts {
+				tourOf = append(tourOf, tour.Number)
+			}
+		}
+	}
+	var stops []export.Stop
+	for i, c := range concerts {
+		coord, ok := coords[c.Location]
+		if !ok {
+			continue
+		}
+		stops = append(stops, export.Stop{
+			Order: i + 1, Tour: tourOf[i], ArtistID: artist.ID, ArtistName: artist.Name,
+			Location: c.Location, Date: c.Date, Lat: coord.Lat, Lon: coord.Lon,
+		})
+	}
+	return stops
+}
+
+// writeTourGeoJSON serves the tours of an artist as GeoJSON, with a route
+// for each tour.
+func writeTourGeoJSON(w http.ResponseWriter, artist *models.Artists, relations *models.Relations, dates *models.Dates) {
+	w.Header().Set("Content-Type", "application/geo+json")
+	export.WriteGeoJSON(w, export.TourFeatures(artistStops(artist, relations, dates)))
+}
+
+// writeTourKML serves the tour of an artist as a KML download.
+func writeTourKML(w http.ResponseWriter, artist *models.Artists, relations *models.Relations, dates *models.Dates) {
+	w.Header().Set("Content-Type", "application/vnd.google-earth.kml+xml")
+	w.Header().Set("Content-Disposition", `attachment; filename="tour.kml"`)
+	export.WriteKML(w, artist.Name+" tour", artistStops(artist, relations, dates))
+}
+
+// writeTourGPX serves the tour of an artist as a GPX download.
+func writeTourGPX(w http.ResponseWriter, artist *models.Artists, relations *models.Relations, dates *models.Dates) {
+	w.Header().Set("Content-Type", "application/gpx+xml")
+	w.Header().Set("Content-Disposition", `attachment; filename="tour.gpx"`)
+	export.WriteGPX(w, artist.Name+" tour", artistStops(artist, relations, dates), time.Now())
+}
+
+// ConcertsGeoJSONHandler serves concerts of every artist as GeoJSON points.
+// They can be filtered with the query parameters artist (an ID), location,
+// country, and from and to (dates as YYYY-MM-DD, both inclusive).
+func ConcertsGeoJSONHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		HandleErrors(w, http.StatusMethodNotAllowed, http.StatusText(http.StatusMethodNotAllowed), "This request method is not supported for the requested resource. Use GET request instead.")
+		return
+	}
+	if !api.GetLoadingStatus().IsLoaded {
+		w.Header().Set("Retry-After", "30")
+		HandleErrors(w, http.StatusServiceUnavailable, http.StatusText(http.StatusServiceUnavailable), "The concert data is not loaded yet. Please try again later.")
+		return
+	}
+	q := r.URL.Query()
+	artistID := 0
+	if v := q.Get("artist"); v != "" {
+		id, err := strconv.Atoi(v)
+		if err != nil {
+			HandleErrors(w, http.StatusBadRequest, http.StatusText(http.StatusBadRequest), "The artist parameter must be an artist ID.")
+			return
+		}
+		artistID = id
+	}
+	from, errFrom := parseFilterDate(q.Get("from"))
+	to, errTo := parseFilterDate(q.Get("to"))
+	if errFrom != nil || errTo != nil {
+		HandleErrors(w, http.StatusBadRequest, http.StatusText(http.StatusBadRequest), "Dates must be given as YYYY-MM-DD.")
+		return
+	}
+
+	match := func(string) bool { return true }
+	if loc := q.Get("location"); loc != "" {
+		match = services.AtLocation(loc)
+	}
+	if country := q.Get("country"); country != "" {
+		inLocation, inCountry := match, services.InCountry(country)
+		match = func(l string) bool { return inLocation(l) && inCountry(l) }
+	}
+
+	concerts := services.ConcertsWhere(match)
+	locations := make([]string, 0, len(concerts))
+	for _, c := range concerts {
+		locations = append(locations, c.Location)
+	}
+	coords := services.CachedCoordinates(locations)
+	var stops []export.Stop
+	for _, c := range concerts {
+		coord, mapped := coords[c.Location]
+		switch {
+		case !mapped,
+			artistID != 0 && c.ArtistID != artistID,
+			!from.IsZero() && c.Date.Before(from),
+			!to.IsZero() && c.Date.After(to):
+			continue
+		}
+		stops = append(stops, export.Stop{
+			Order: len(stops) + 1, ArtistID: c.ArtistID, ArtistName: c.ArtistName,
+			Location: c.Location, Date: c.Date, Lat: coord.Lat, Lon: coord.Lon,
+		})
+	}
+	w.Header().Set("Content-Type", "application/geo+json")
+	export.WriteGeoJSON(w, export.StopFeatures(stops))
+}
+
+// parseFilterDate reads an optional YYYY-MM-DD date.
+func parseFilterDate(s string) (time.Time, error) {
+	if s = strings.TrimSpace(s); s == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse("2006-01-02", s)
+}
@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"groupie-tracker/models"
+	"groupie-tracker/search"
+)
+
+// searchResponse is what /api/search returns for a valid query. Results
+// holds a page of []search.SearchResult, or of []search.ArtistGroup in
+// grouped mode; Total counts them all and Next is the cursor of the next
+// page.
+type searchResponse struct {
+	Results    any                `json:"results"`
+	Total      int                `json:"total"`
+	Next       string             `json:"next,omitempty"`
+	Facets     search.Facets      `json:"facets"`
+	Suggestion *search.Suggestion `json:"suggestion,omitempty"` // when nothing is found
+}
+
+// facetGroup is one facet of the search on the home page.
+type facetGroup struct {
+	Title  string
+	Values []facetLink
+}
+
+// facetLink refines the search to one facet value.
+type facetLink struct {
+	Label   string
+	Count   int
+	Href    string
+	Current bool // the category picked already
+}
+
+// categoryLabels name the categories as the search form does.
+var categoryLabels = map[string]string{
+	"artist":        "Artist/Band",
+	"member":        "Member",
+	"first_album":   "First Album",
+	"creation_date": "Creation Date",
+	"concert":       "Concert",
+}
+
+// runSearch runs a search on ix with the category picked in the form
+// (see parseCategory), keeps the results whose artist matches the filters,
+// and counts their facets. Errors are *search.SyntaxError.
+func runSearch(ix *search.Index, query, category string, filter models.ArtistFilter) ([]search.SearchResult, search.Facets, error) {
+	q, err := search.Parse(query)
+	if err != nil {
+		return nil, search.Facets{}, err
+	}
+	q = withCategory(q, category)
+	results := filterResults(ix.Run(q), filter)
+	// Categories are counted as if none was picked, to show what each gives;
+	// without a category clause, that is what was just run
+	all := results
+	if without := q.WithoutCategories(); len(without.Clauses) < len(q.Clauses) {
+		all = filterResults(ix.Run(without), filter)
+	}
+	return results, ix.Facets(all, results), nil
+}
+
+// didYouMean returns the correction of a search that found nothing, if
+// the corrected search finds something with the same category and filters.
+func didYouMean(ix *search.Index, query, category string, filter models.ArtistFilter) *search.Suggestion {
+	s := ix.Suggest(query)
+	if s == nil {
+		return nil
+	}
+	// Only whether it finds anything matters: facets aren't counted
+	q, err := search.Parse(s.Query)
+	if err != nil || len(filterResults(ix.Run(withCategory(q, category)), filter)) == 0 {
+		return nil
+	}
+	return s
+}
+
+// homeHref links to the home page searching query in category with
+// filter applied.
+func homeHref(query, category string, filter models.ArtistFilter) string {
+	params := filterQuery(filter)
+	params.Set("search", query)
+	if category != "" {
+		params.Set("category", category)
+	}
+	return "/?" + params.Encode()
+}
+
+// facetGroups links each facet value to the search refined by it: the
+// category in the category field, other facets as a clause added to the
+// query. The picked category and filters are kept.
+func facetGroups(f search.Facets, query, picked string, filter models.ArtistFilter) []facetGroup {
+
+	categories := facetGroup{Title: "Category"}
+	for _, v := range f.Categories {
+		categories.Values = append(categories.Values, facetLink{
+			Label:   categoryLabels[v.Value],
+			Count:   v.Count,
+			Href:    homeHref(query, v.Value, filter),
+			Current: v.Value == picked,
+		})
+	}
+	links := func(title string, values []search.FacetValue, label func(string) string) facetGroup {
+		g := facetGroup{Title: title}
+		for _, v := range values {
+			g.Values = append(g.Values, facetLink{Label: label(v.Value), Count: v.Count, Href: homeHref(query+" "+v.Clause, picked, filter)})
+		}
+		return g
+	}
+	same := func(s string) string { return s }
+	members := func(s string) string {
+		if s == "1" {
+			return "Solo"
+		}
+		return s + " members"
+	}
+
+	var groups []facetGroup
+	for _, g := range []facetGroup{
+		categories,
+		links("Country", f.Countries, same),
+		links("Created in", f.Decades, same),
+		links("Members", f.Members, members),
+	} {
+		if len(g.Values) > 0 {
+			groups = append(groups, g)
+		}
+	}
+	return groups
+}
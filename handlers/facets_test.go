@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"fmt"
+	"testing"
+
+	"groupie-tracker/models"
+	"groupie-tracker/search"
+)
+
+// benchmarkIndex indexes a dataset about the size of the real API's.
+func benchmarkIndex() *search.Index {
+	countries := []string{"usa", "uk", "germany", "france", "japan", "australia", "brazil", "canada"}
+	var artists []models.Artists
+	relations := map[int]*models.Relations{}
+	for id := 1; id <= 52; id++ {
+		artists = append(artists, models.Artists{
+			ID:           id,
+			Name:         fmt.Sprintf("Band %d", id),
+			Members:      []string{fmt.Sprintf("Singer%d Smith", id), fmt.Sprintf("Drummer%d Jones", id), fmt.Sprintf("Bassist%d Brown", id)},
+			CreationDate: 1960 + id%50,
+			FirstAlbum:   fmt.Sprintf("%02d-%02d-%d", 1+id%28, 1+id%12, 1965+id%50),
+		})
+		rel := &models.Relations{ID: id, DatesLocations: map[string][]string{}}
+		for i := 0; i < 10; i++ {
+			loc := fmt.Sprintf("city_%d-%s", (id*7+i)%120, countries[(id+i)%len(countries)])
+			rel.SortedLocations = append(rel.SortedLocations, loc)
+			for d := 0; d < 3; d++ {
+				rel.DatesLocations[loc] = append(rel.DatesLocations[loc], fmt.Sprintf("%02d-%02d-20%02d", 1+d*9, 1+i, 10+id%10))
+			}
+		}
+		relations[id] = rel
+	}
+	return search.NewIndex(artists, func(id int) (*models.Relations, error) { return relations[id], nil })
+}
+
+// BenchmarkRunSearch times a search of the home page or /api/search with
+// its facets, which runs the query a second time when a category is picked.
+func BenchmarkRunSearch(b *testing.B) {
+	ix := benchmarkIndex()
+	for _, bench := range []struct{ query, category string }{
+		{"smith", ""},
+		{"japan", ""},
+		{"ci", ""},
+		{"ci", "concert"},
+	} {
+		name := bench.query
+		if bench.category != "" {
+			name += "/category=" + bench.category
+		}
+		b.Run(name, func(b *testing.B) {
+			var results []search.SearchResult
+			for i := 0; i < b.N; i++ {
+				var err error
+				if results, _, err = runSearch(ix, bench.query, bench.category, models.ArtistFilter{}); err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.ReportMetric(float64(len(results)), "results/op")
+		})
+	}
+}
+
+// BenchmarkDidYouMean times what a search that finds nothing adds: the
+// suggestion, and the search for it that checks it finds something.
+func BenchmarkDidYouMean(b *testing.B) {
+	ix := benchmarkIndex()
+	const query = "jnoez"
+	if results, _, err := runSearch(ix, query, "", models.ArtistFilter{}); err != nil || len(results) > 0 {
+		b.Fatalf("runSearch(%q) = %d results, %v; want none", query, len(results), err)
+	}
+	if didYouMean(ix, query, "", models.ArtistFilter{}) == nil {
+		b.Fatalf("didYouMean(%q) = nil, want a suggestion", query)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		didYouMean(ix, query, "", models.ArtistFilter{})
+	}
+}
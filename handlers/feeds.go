@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"groupie-tracker/api"
+	"groupie-tracker/export"
+	"groupie-tracker/models"
+	"groupie-tracker/services"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// FeedHandler serves subscribable calendars of every concert at a location
+// (/feeds/location.ics?name=Paris, France) or in a country
+// (/feeds/country.ics?name=France).
+func FeedHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		HandleErrors(w, http.StatusMethodNotAllowed, http.StatusText(http.StatusMethodNotAllowed), "This request method is not supported for the requested resource. Use GET request instead.")
+		return
+	}
+	if !api.GetLoadingStatus().IsLoaded {
+		// calendar apps can't follow the loading page, ask them to retry
+		w.Header().Set("Retry-After", "30")
+		HandleErrors(w, http.StatusServiceUnavailable, http.StatusText(http.StatusServiceUnavailable), "The concert data is not loaded yet. Please try again later.")
+		return
+	}
+	name := strings.TrimSpace(r.URL.Query().Get("name"))
+	if name == "" {
+		HandleErrors(w, http.StatusBadRequest, http.StatusText(http.StatusBadRequest), "Please name a location or country with the name parameter.")
+		return
+	}
+	var concerts []models.ArtistConcert
+	switch r.URL.Path {
+	case "/feeds/location.ics":
+		concerts = services.ConcertsWhere(services.AtLocation(name))
+	case "/feeds/country.ics":
+		concerts = services.ConcertsWhere(services.InCountry(name))
+	default:
+		HandleErrors(w, http.StatusNotFound, http.StatusText(http.StatusNotFound), "Please check the resource URL and try again.")
+		return
+	}
+	writeCalendar(w, "Concerts in "+name, concerts)
+}
+
+// writeCalendar writes concerts as an iCalendar feed, with coordinates from
+// the geocode cache where they are known. Each concert lasts its local day,
+// in the timezone of its location, so calendar apps elsewhere show it at
+// the hours that day covers there.
+func writeCalendar(w http.ResponseWriter, name string, concerts []models.ArtistConcert) {
+	locations := make([]string, 0, len(concerts))
+	for _, c := range concerts {
+		locations = append(locations, c.Location)
+	}
+	coords := services.CachedCoordinates(locations)
+
+	events := make([]export.Event, 0, len(concerts))
+	for _, c := range concerts {
+		c.TimeZone = services.ConcertTimeZone(c.Location, coords)
+		e := export.Event{
+			UID:      services.ConcertUID(c.ArtistID, c.Concert),
+			Summary:  c.ArtistName + " in " + c.Location,
+			Location: c.Location,
+			Date:     c.Date,
+		}
+		e.Start, e.End = services.ConcertDay(c.Concert)
+		if coord, ok := coords[c.Location]; ok {
+			e.HasGeo, e.Lat, e.Lon = true, coord.Lat, coord.Lon
+		}
+		events = append(events, e)
+	}
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	export.WriteICS(w, name, events, time.Now())
+}
+
+// artistConcerts lists the concerts of one artist for its calendar feed.
+func artistConcerts(artist *models.Artists, relations *models.Relations) []models.ArtistConcert {
+	concerts := services.Concerts(relations)
+	list := make([]models.ArtistConcert, 0, len(concerts))
+	for _, c := range concerts {
+		list = append(list, models.ArtistConcert{ArtistID: artist.ID, ArtistName: artist.Name, Concert: c})
+	}
+	return list
+}
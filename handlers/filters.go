@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"fmt"
+	"groupie-tracker/models"
+	"groupie-tracker/search"
+	"groupie-tracker/services"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// filtersView is what the home page needs to render its filters.
+type filtersView struct {
+	models.ArtistFilter
+	Options         models.FilterOptions
+	MemberChoices   []memberChoice
+	LocationChoices []locationChoice
+	Active          bool
+}
+
+type memberChoice struct {
+	Count   int
+	Label   string
+	Checked bool
+}
+
+type locationChoice struct {
+	Name     string
+	Selected bool
+}
+
+// parseArtistFilter reads the home page filters from the URL query:
+// creation_from, creation_to, album_from and album_to as years, members
+// (repeatable) as member counts and location (repeatable) as formatted
+// location names. A range may be open at either end, but not inverted.
+func parseArtistFilter(q url.Values) (models.ArtistFilter, error) {
+	var f models.ArtistFilter
+	years := []struct {
+		param  string
+		target *int
+	}{
+		{"creation_from", &f.CreationFrom}, {"creation_to", &f.CreationTo},
+		{"album_from", &f.AlbumFrom}, {"album_to", &f.AlbumTo},
+	}
+	for _, y := range years {
+		v := strings.TrimSpace(q.Get(y.param))
+		if v == "" {
+			continue
+		}
+		year, err := strconv.Atoi(v)
+		if err != nil || year <= 0 {
+			return f, fmt.Errorf("%s must be a year", y.param)
+		}
+		*y.target = year
+	}
+	if f.CreationFrom != 0 && f.CreationTo != 0 && f.CreationFrom > f.CreationTo {
+		return f, fmt.Errorf("creation_from must not be after creation_to")
+	}
+	if f.AlbumFrom != 0 && f.AlbumTo != 0 && f.AlbumFrom > f.AlbumTo {
+		return f, fmt.Errorf("album_from must not be after album_to")
+	}
+	for _, v := range q["members"] {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			return f, fmt.Errorf("members must be a number of members")
+		}
+		f.Members = append(f.Members, n)
+	}
+	for _, v := range q["location"] {
+		if v = strings.TrimSpace(v); v != "" {
+			f.Locations = append(f.Locations, v)
+		}
+	}
+	return f, nil
+}
+
+// filterQuery encodes f as the URL query parseArtistFilter reads, so links
+// to a filtered view can be shared.
+func filterQuery(f models.ArtistFilter) url.Values {
+	q := url.Values{}
+	years := []struct {
+		param string
+		year  int
+	}{
+		{"creation_from", f.CreationFrom}, {"creation_to", f.CreationTo},
+		{"album_from", f.AlbumFrom}, {"album_to", f.AlbumTo},
+	}
+	for _, y := range years {
+		if y.year != 0 {
+			q.Set(y.param, strconv.Itoa(y.year))
+		}
+	}
+	for _, n := range f.Members {
+		q.Add("members", strconv.Itoa(n))
+	}
+	for _, loc := range f.Locations {
+		q.Add("location", loc)
+	}
+	return q
+}
+
+// newFiltersView prepares the filters form for f.
+func newFiltersView(f models.ArtistFilter) filtersView {
+	view := filtersView{ArtistFilter: f, Options: services.CurrentFilterOptions(), Active: services.FilterActive(f)}
+	for _, n := range view.Options.MemberCounts {
+		label := strconv.Itoa(n)
+		if n == 1 {
+			label = "Solo"
+		}
+		view.MemberChoices = append(view.MemberChoices, memberChoice{Count: n, Label: label, Checked: containsInt(f.Members, n)})
+	}
+	selected := make(map[string]bool)
+	for _, loc := range f.Locations {
+		selected[loc] = true
+	}
+	for _, loc := range view.Options.Locations {
+		view.LocationChoices = append(view.LocationChoices, locationChoice{Name: loc, Selected: selected[loc]})
+	}
+	return view
+}
+
+func containsInt(list []int, v int) bool {
+	for _, n := range list {
+		if n == v {
+			return true
+		}
+	}
+	return false
+}
+
+// filterResults keeps the search results whose artist matches f.
+func filterResults(results []search.SearchResult, f models.ArtistFilter) []search.SearchResult {
+	if !services.FilterActive(f) {
+		return results
+	}
+	matches := make(map[int]bool)
+	filtered := []search.SearchResult{}
+	for _, r := range results {
+		match, seen := matches[r.ID]
+		if !seen {
+			artist, err := services.GetArtistByID(r.ID)
+			match = err == nil && services.MatchesFilter(*artist, f)
+			matches[r.ID] = match
+		}
+		if match {
+			filtered = append(filtered, r)
+		}
+	}
+	return filtered
+}
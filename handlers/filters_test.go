@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+
+	"groupie-tracker/models"
+)
+
+func TestParseArtistFilter(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   string
+		want    models.ArtistFilter
+		wantErr bool
+	}{
+		{"no filters", "", models.ArtistFilter{}, false},
+		{"ranges", "creation_from=1970&creation_to=1990&album_from=1980&album_to=2000",
+			models.ArtistFilter{CreationFrom: 1970, CreationTo: 1990, AlbumFrom: 1980, AlbumTo: 2000}, false},
+		{"open ranges", "creation_from=1970&album_to=2000", models.ArtistFilter{CreationFrom: 1970, AlbumTo: 2000}, false},
+		{"single year", "creation_from=1985&creation_to=1985", models.ArtistFilter{CreationFrom: 1985, CreationTo: 1985}, false},
+		{"spaces around a year", "creation_from=+1970+", models.ArtistFilter{CreationFrom: 1970}, false},
+		{"empty range ends", "creation_from=&creation_to=", models.ArtistFilter{}, false},
+		{"inverted creation range", "creation_from=1990&creation_to=1970", models.ArtistFilter{}, true},
+		{"inverted album range", "album_from=2000&album_to=1980", models.ArtistFilter{}, true},
+		{"year not a number", "creation_from=sixties", models.ArtistFilter{}, true},
+		{"year not positive", "album_to=0", models.ArtistFilter{}, true},
+		{"member counts", "members=1&members=4", models.ArtistFilter{Members: []int{1, 4}}, false},
+		{"unknown member count", "members=99", models.ArtistFilter{Members: []int{99}}, false},
+		{"member count not a number", "members=four", models.ArtistFilter{}, true},
+		{"member count not positive", "members=0", models.ArtistFilter{}, true},
+		{"repeated locations", "location=London,+UK&location=Paris,+France",
+			models.ArtistFilter{Locations: []string{"London, UK", "Paris, France"}}, false},
+		{"blank location", "location=+&location=Paris,+France", models.ArtistFilter{Locations: []string{"Paris, France"}}, false},
+		{"everything", "search=queen&creation_to=1975&members=4&location=London,+UK",
+			models.ArtistFilter{CreationTo: 1975, Members: []int{4}, Locations: []string{"London, UK"}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, err := url.ParseQuery(tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := parseArtistFilter(q)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseArtistFilter(%q) error = %v, want error %v", tt.query, err, tt.wantErr)
+			}
+			if err == nil && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseArtistFilter(%q) = %+v, want %+v", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilterQueryRoundTrip(t *testing.T) {
+	tests := []struct {
+		filter models.ArtistFilter
+		want   string
+	}{
+		{models.ArtistFilter{}, ""},
+		{models.ArtistFilter{CreationFrom: 1970, CreationTo: 1990}, "creation_from=1970&creation_to=1990"},
+		{models.ArtistFilter{AlbumTo: 2000}, "album_to=2000"},
+		{models.ArtistFilter{Members: []int{1, 4}}, "members=1&members=4"},
+		{models.ArtistFilter{Locations: []string{"London, UK", "Paris, France"}}, "location=London%2C+UK&location=Paris%2C+France"},
+		{
+			models.ArtistFilter{CreationFrom: 1960, AlbumFrom: 1965, AlbumTo: 1980, Members: []int{5}, Locations: []string{"Tokyo, Japan"}},
+			"album_from=1965&album_to=1980&creation_from=1960&location=Tokyo%2C+Japan&members=5",
+		},
+	}
+	for _, tt := range tests {
+		q := filterQuery(tt.filter)
+		if got := q.Encode(); got != tt.want {
+			t.Errorf("filterQuery(%+v) = %q, want %q", tt.filter, got, tt.want)
+		}
+		back, err := parseArtistFilter(q)
+		if err != nil || !reflect.DeepEqual(back, tt.filter) {
+			t.Errorf("parseArtistFilter(filterQuery(%+v)) = %+v, %v; want it back", tt.filter, back, err)
+		}
+	}
+}
+
+func TestHomeHrefKeepsFilters(t *testing.T) {
+	filter := models.ArtistFilter{CreationFrom: 1970, Members: []int{4}, Locations: []string{"London, UK"}}
+	href := homeHref("queen country:uk", "concert", filter)
+	q := mustQuery(t, href)
+	if q.Get("search") != "queen country:uk" || q.Get("category") != "concert" {
+		t.Errorf("homeHref = %q, want the home page searching queen country:uk in concerts", href)
+	}
+	if got, err := parseArtistFilter(q); err != nil || !reflect.DeepEqual(got, filter) {
+		t.Errorf("filters of %q = %+v, %v; want %+v", href, got, err, filter)
+	}
+	if q := mustQuery(t, homeHref("queen", "", models.ArtistFilter{})); len(q) != 1 {
+		t.Errorf("homeHref without category or filters = %v, want only search", q)
+	}
+}
+
+// mustQuery returns the query of href, which must link to the home page.
+func mustQuery(t *testing.T, href string) url.Values {
+	t.Helper()
+	u, err := url.Parse(href)
+	if err != nil || u.Path != "/" {
+		t.Fatalf("%q does not link to the home page: %v", href, err)
+	}
+	return u.Query()
+}
@@ -16,12 +16,17 @@ import (
 )
 
 // Parse templates once before server startup
-var (
-	index_tmpl   = template.Must(template.ParseFiles("templates/index.html"))
-	artist_tmpl  = template.Must(template.ParseFiles("templates/artist_detail.html"))
-	error_tmpl   = template.Must(template.ParseFiles("templates/error.html"))
+var index_tmpl, artist_tmpl, error_tmpl, loading_tmpl *template.Template
+
+// init parses the templates from the working directory. It runs after the
+// package variables are set, so tests can move to the repository root in
+// one of theirs.
+func init() {
+	index_tmpl = template.Must(template.ParseFiles("templates/index.html"))
+	artist_tmpl = template.Must(template.ParseFiles("templates/artist_detail.html"))
+	error_tmpl = template.Must(template.ParseFiles("templates/error.html"))
 	loading_tmpl = template.Must(template.ParseFiles("templates/loading.html"))
-)
+}
 
 func HomeHandler(w http.ResponseWriter, r *http.Request) {
 	if r.URL.Path != "/" {
@@ -39,25 +44,48 @@ func HomeHandler(w http.ResponseWriter, r *http.Request) {
 		HandleErrors(w, http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError), "The server was unable to load the data. Please try again later.")
 		return
 	}
+	filter, err := parseArtistFilter(r.URL.Query())
+	if err != nil {
+		HandleErrors(w, http.StatusBadRequest, http.StatusText(http.StatusBadRequest), "Please check the filters: "+err.Error()+".")
+		return
+	}
+	category, err := parseCategory(r.URL.Query())
+	if err != nil {
+		HandleErrors(w, http.StatusBadRequest, http.StatusText(http.StatusBadRequest), "Please check the category: "+err.Error()+".")
+		return
+	}
 	query := r.URL.Query().Get("search")
 	var SearchResults []search.SearchResult
+	var SearchError string
+	var Facets []facetGroup
+	ix := search.Current()
 	if query != "" {
-		SearchResults = search.Search(query, api.All_Artists, services.GetRelationsByID)
-	}
-	category := r.URL.Query().Get("category")
-	if category != "" && category != "all" {
-		SearchResults = search.FilterSearch(SearchResults, category)
+		results, facets, err := runSearch(ix, query, category, filter)
+		if err != nil {
+			SearchError = err.Error()
+		} else if len(results) > 0 {
+			Facets = facetGroups(facets, query, category, filter)
+		}
+		SearchResults = results
 	}
 	data := struct {
 		Artists       []models.Artists
 		SearchQuery   string
 		SearchResults []search.SearchResult
+		SearchError   string
 		NoResults	  bool
+		Suggestion     *search.Suggestion
+		SuggestionHref string
+		Facets        []facetGroup
+		Filters       filtersView
 	}{
 		Artists:       api.All_Artists,
 		SearchQuery:   query,
 		SearchResults: SearchResults,
+		SearchError:   SearchError,
 		NoResults:     false,
+		Facets:        Facets,
+		Filters:       newFiltersView(filter),
 	}
 	// If query exists and SearchResults != empty, show search results only
 	if query != "" && len(SearchResults) > 0 {
@@ -69,9 +97,17 @@ func HomeHandler(w http.ResponseWriter, r *http.Request) {
 				data.Artists = append(data.Artists, *artist)
 			}
 		}
-	} else if query != "" && len(SearchResults) == 0 {
+	} else if query != "" && len(SearchResults) == 0 && SearchError == "" {
 		data.SearchResults = []search.SearchResult{}
 		data.NoResults = true
+		data.Artists = services.FilterArtists(api.All_Artists, filter)
+		if data.Suggestion = didYouMean(ix, query, category, filter); data.Suggestion != nil {
+			data.SuggestionHref = homeHref(data.Suggestion.Query, category, filter)
+		}
+	} else {
+		// No search, or an invalid one: list the artists the filters allow
+		data.Artists = services.FilterArtists(api.All_Artists, filter)
+		data.NoResults = len(data.Artists) == 0 && SearchError == ""
 	}
 	if err := index_tmpl.Execute(w, data); err != nil {
 		HandleErrors(w, http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError), "The server was unable to complete your request. Please try again later")
@@ -95,7 +131,15 @@ func ArtistDetailsHandler(w http.ResponseWriter, r *http.Request) {
 		HandleErrors(w, http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError), "The server was unable to load the data. Please try again later.")
 		return
 	}
-	artist_ID, _ := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/artist/"))
+	// "/artist/{id}" is the page; "/artist/{id}/{resource}" serves its data
+	idPart, resource, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/artist/"), "/")
+	artist_ID, _ := strconv.Atoi(idPart)
+	switch resource {
+	case "", "stats.json", "concerts.ics", "tour.geojson", "tour.kml", "tour.gpx":
+	default:
+		HandleErrors(w, http.StatusNotFound, http.StatusText(http.StatusNotFound), "Please check the resource URL and try again.")
+		return
+	}
 
 	artist, err := services.GetArtistByID(artist_ID)
 	if err != nil {
@@ -117,13 +161,40 @@ func ArtistDetailsHandler(w http.ResponseWriter, r *http.Request) {
 		HandleErrors(w, http.StatusNotFound, http.StatusText(http.StatusNotFound), err.Error())
 		return
 	}
-	mapData := services.Geocode(relations.SortedLocations)
+	switch resource {
+	case "concerts.ics":
+		writeCalendar(w, artist.Name+" concerts", artistConcerts(artist, relations))
+		return
+	case "tour.geojson":
+		writeTourGeoJSON(w, artist, relations, dates)
+		return
+	case "tour.kml":
+		writeTourKML(w, artist, relations, dates)
+		return
+	case "tour.gpx":
+		writeTourGPX(w, artist, relations, dates)
+		return
+	}
+	mapData := services.GeocodeContext(r.Context(), relations.SortedLocations)
+	concerts := services.Concerts(relations)
+	services.LocalizeConcerts(concerts, mapData)
+	stats := services.TourStats(concerts, mapData)
+	tours := services.SegmentTours(concerts, dates)
+	services.AddTourDistances(tours, stats.Hops)
+	if resource == "stats.json" {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(stats)
+		return
+	}
 	data := models.ArtistDetails{
 		Artist:    *artist,
 		Locations: *locations,
 		Dates:     *dates,
 		Relations: *relations,
-		MapData:   mapData,
+		MapPoints: services.MapPoints(relations.SortedLocations, mapData),
+		Stats:     stats,
+		Anomalies: services.TravelAnomalies(concerts, mapData),
+		Tours:     tours,
 	}
 	if err := artist_tmpl.Execute(w, data); err != nil {
 		HandleErrors(w, http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError), "The server was unable to complete your request. Please try again later")
@@ -189,24 +260,102 @@ func LoadingHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-// SearchHandler returns search results in JSON format based on the query parameter.
+// SearchHandler returns search results in JSON format based on the query parameter,
+// as {"results": [...], "total": n, "next": "cursor", "facets": {...}}. It takes the
+// home page filters too, limit and cursor to page through the results, per_category
+// to cap each category and group=artist for one result per artist.
 // It is used for Javascript-based search autocomplete functionality.
 func SearchHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		HandleErrors(w, http.StatusMethodNotAllowed, http.StatusText(http.StatusMethodNotAllowed), "This request method is not supported for the requested resource. Use GET request instead.")
 		return
 	}
+	w.Header().Set("Content-Type", "application/json")
+	filter, err := parseArtistFilter(r.URL.Query())
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Please check the filters: " + err.Error() + "."})
+		return
+	}
+	category, err := parseCategory(r.URL.Query())
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Please check the category: " + err.Error() + "."})
+		return
+	}
+	page, err := parseSearchPage(r.URL.Query())
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
 	query := r.URL.Query().Get("search")
 	if query == "" {
-		w.Header().Set("Content-Type", "application/json")
-		w.Write([]byte("[]"))
+		json.NewEncoder(w).Encode(searchResponse{Results: []search.SearchResult{}, Facets: search.Current().Facets(nil, nil)})
 		return
 	}
-	SearchResults := search.Search(query, api.All_Artists, services.GetRelationsByID)
-	category := r.URL.Query().Get("category")
-	if category != "" && category != "all" {
-		SearchResults = search.FilterSearch(SearchResults, category)
+	ix := search.Current()
+	SearchResults, facets, err := runSearch(ix, query, category, filter)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	response := searchResponse{Facets: facets}
+	if len(SearchResults) == 0 {
+		response.Suggestion = didYouMean(ix, query, category, filter)
+	}
+	if page.grouped {
+		groups := ix.Group(SearchResults, groupReasons)
+		response.Total = len(groups)
+		response.Results, response.Next, err = pageOf(groups, page)
+	} else {
+		if page.perCategory > 0 {
+			SearchResults = search.CapCategories(SearchResults, page.perCategory)
+		}
+		response.Total = len(SearchResults)
+		response.Results, response.Next, err = pageOf(SearchResults, page)
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+// parseCategory reads the category picked in the search form: one of
+// search.Categories, or "" for all of them.
+func parseCategory(q url.Values) (string, error) {
+	category := strings.ToLower(strings.TrimSpace(q.Get("category")))
+	if category == "" || category == "all" {
+		return "", nil
+	}
+	for _, c := range search.Categories {
+		if category == c {
+			return category, nil
+		}
+	}
+	return "", fmt.Errorf("category must be all or one of %s", strings.Join(search.Categories, ", "))
+}
+
+// withCategory adds the category picked in the search form, if any, to the
+// query as a category: clause.
+func withCategory(q search.Query, category string) search.Query {
+	if category != "" {
+		q.Clauses = append(q.Clauses, search.Clause{Field: "category", Text: category})
+	}
+	return q
+}
+
+// GeocodeStatusHandler reports the progress of background geocoding in JSON
+// format. The loading page polls it.
+func GeocodeStatusHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		HandleErrors(w, http.StatusMethodNotAllowed, http.StatusText(http.StatusMethodNotAllowed), "This request method is not supported for the requested resource. Use GET request instead.")
+		return
 	}
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(SearchResults)
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(services.GeocodeStatus())
 }
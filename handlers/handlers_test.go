@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+
+	"groupie-tracker/search"
+)
+
+// The templates are parsed from the repository root by init, which runs
+// after this.
+var _ = os.Chdir("..")
+
+func TestParseCategory(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{"", "", false},
+		{"all", "", false},
+		{"concert", "concert", false},
+		{" Member ", "member", false},
+		{"band", "", true},
+		{`concert" queen`, "", true},
+		{"concert category:artist", "", true},
+	}
+	for _, tt := range tests {
+		got, err := parseCategory(url.Values{"category": {tt.value}})
+		if got != tt.want || (err != nil) != tt.wantErr {
+			t.Errorf("parseCategory(%q) = %q, %v; want %q, error %v", tt.value, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestWithCategory(t *testing.T) {
+	q, err := search.Parse("queen")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := withCategory(q, ""); len(got.Clauses) != 1 {
+		t.Errorf("withCategory(all) = %+v, want the query as it is", got.Clauses)
+	}
+	got := withCategory(q, "member")
+	if want := (search.Clause{Field: "category", Text: "member"}); len(got.Clauses) != 2 || got.Clauses[1] != want {
+		t.Errorf("withCategory(member) = %+v, want queen and %+v", got.Clauses, want)
+	}
+}
+
+func TestSearchHandlerRejectsCategory(t *testing.T) {
+	w := httptest.NewRecorder()
+	SearchHandler(w, httptest.NewRequest(http.MethodGet, "/api/search?search=queen&category=band", nil))
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "category must be") {
+		t.Errorf("SearchHandler(category=band) = %d %s, want 400 about the category", w.Code, w.Body)
+	}
+}
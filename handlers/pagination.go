@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"strconv"
+)
+
+const (
+	defaultSearchLimit = 20
+	maxSearchLimit     = 100
+	// groupReasons is how many matches each artist shows in grouped mode.
+	groupReasons = 3
+)
+
+// searchPage is the part of the results /api/search returns.
+type searchPage struct {
+	limit       int
+	offset      int  // from the cursor
+	perCategory int  // 0 for no cap
+	grouped     bool // one entry per artist
+}
+
+// parseSearchPage reads limit, cursor, per_category and group=artist from
+// the URL query.
+func parseSearchPage(q url.Values) (searchPage, error) {
+	p := searchPage{limit: defaultSearchLimit}
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 || n > maxSearchLimit {
+			return p, fmt.Errorf("limit must be a number from 1 to %d", maxSearchLimit)
+		}
+		p.limit = n
+	}
+	if v := q.Get("cursor"); v != "" {
+		offset, err := decodeCursor(v)
+		if err != nil {
+			return p, fmt.Errorf("cursor must come from the next field of a previous response")
+		}
+		p.offset = offset
+	}
+	if v := q.Get("per_category"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			return p, fmt.Errorf("per_category must be a positive number")
+		}
+		p.perCategory = n
+	}
+	switch q.Get("group") {
+	case "":
+	case "artist":
+		p.grouped = true
+	default:
+		return p, fmt.Errorf("group must be artist")
+	}
+	return p, nil
+}
+
+// Cursors are opaque to clients: they hold the offset of the next page.
+func encodeCursor(offset int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.Itoa(offset)))
+}
+
+// decodeCursor accepts only what encodeCursor writes, so an edited cursor
+// is an error rather than some other page.
+func decodeCursor(s string) (int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return 0, err
+	}
+	offset, err := strconv.Atoi(string(b))
+	if err != nil || offset <= 0 || strconv.Itoa(offset) != string(b) {
+		return 0, fmt.Errorf("invalid cursor %q", s)
+	}
+	return offset, nil
+}
+
+// pageOf returns the page p of items, and the cursor of the next page, or
+// "" on the last one. A cursor past the last item is an error: no response
+// hands one out.
+func pageOf[T any](items []T, p searchPage) ([]T, string, error) {
+	if p.offset == 0 && len(items) == 0 {
+		return []T{}, "", nil
+	}
+	if p.offset >= len(items) {
+		return nil, "", fmt.Errorf("cursor is past the last of the %d results", len(items))
+	}
+	end := p.offset + p.limit
+	if end >= len(items) {
+		return items[p.offset:], "", nil
+	}
+	return items[p.offset:end], encodeCursor(end), nil
+}
@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseSearchPage(t *testing.T) {
+	raw := func(s string) string { return base64.RawURLEncoding.EncodeToString([]byte(s)) }
+	tests := []struct {
+		name    string
+		query   string
+		want    searchPage
+		wantErr bool
+	}{
+		{"defaults", "", searchPage{limit: defaultSearchLimit}, false},
+		{"smallest limit", "limit=1", searchPage{limit: 1}, false},
+		{"largest limit", "limit=100", searchPage{limit: maxSearchLimit}, false},
+		{"limit zero", "limit=0", searchPage{}, true},
+		{"limit negative", "limit=-5", searchPage{}, true},
+		{"limit over the maximum", "limit=101", searchPage{}, true},
+		{"limit not a number", "limit=all", searchPage{}, true},
+		{"cursor", "cursor=" + encodeCursor(40), searchPage{limit: defaultSearchLimit, offset: 40}, false},
+		{"cursor not base64", "cursor=%21%21%21", searchPage{}, true},
+		{"cursor not a number", "cursor=" + raw("forty"), searchPage{}, true},
+		{"cursor negative", "cursor=" + raw("-20"), searchPage{}, true},
+		{"cursor zero", "cursor=" + raw("0"), searchPage{}, true},
+		{"cursor with a sign", "cursor=" + raw("+20"), searchPage{}, true},
+		{"cursor with leading zeros", "cursor=" + raw("020"), searchPage{}, true},
+		{"cursor padded", "cursor=" + base64.URLEncoding.EncodeToString([]byte("20")), searchPage{}, true},
+		{"per category", "per_category=3", searchPage{limit: defaultSearchLimit, perCategory: 3}, false},
+		{"per category zero", "per_category=0", searchPage{}, true},
+		{"grouped", "group=artist&limit=5", searchPage{limit: 5, grouped: true}, false},
+		{"unknown grouping", "group=member", searchPage{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, err := url.ParseQuery(tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := parseSearchPage(q)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseSearchPage(%q) error = %v, want error %v", tt.query, err, tt.wantErr)
+			}
+			if err == nil && got != tt.want {
+				t.Errorf("parseSearchPage(%q) = %+v, want %+v", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCursorRoundTrip(t *testing.T) {
+	for _, offset := range []int{1, 20, 100, 123456} {
+		got, err := decodeCursor(encodeCursor(offset))
+		if err != nil || got != offset {
+			t.Errorf("decodeCursor(encodeCursor(%d)) = %d, %v", offset, got, err)
+		}
+	}
+}
+
+func TestPageOf(t *testing.T) {
+	items := func(n int) []int {
+		out := make([]int, n)
+		for i := range out {
+			out[i] = i
+		}
+		return out
+	}
+	tests := []struct {
+		name      string
+		n         int
+		page      searchPage
+		wantFirst int
+		wantLen   int
+		wantNext  string
+		wantErr   bool
+	}{
+		{"first page", 45, searchPage{limit: 20}, 0, 20, encodeCursor(20), false},
+		{"middle page", 45, searchPage{limit: 20, offset: 20}, 20, 20, encodeCursor(40), false},
+		{"last page", 45, searchPage{limit: 20, offset: 40}, 40, 5, "", false},
+		{"last page full", 40, searchPage{limit: 20, offset: 20}, 20, 20, "", false},
+		{"single page", 3, searchPage{limit: 20}, 0, 3, "", false},
+		{"no results", 0, searchPage{limit: 20}, 0, 0, "", false},
+		{"cursor at the end", 40, searchPage{limit: 20, offset: 40}, 0, 0, "", true},
+		{"cursor past the end", 45, searchPage{limit: 20, offset: 1000}, 0, 0, "", true},
+		{"cursor without results", 0, searchPage{limit: 20, offset: 20}, 0, 0, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, next, err := pageOf(items(tt.n), tt.page)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("pageOf(%d items, %+v) error = %v, want error %v", tt.n, tt.page, err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			want := items(tt.n)[tt.wantFirst : tt.wantFirst+tt.wantLen]
+			if !reflect.DeepEqual(page, want) || next != tt.wantNext {
+				t.Errorf("pageOf(%d items, %+v) = %v, %q; want %v, %q", tt.n, tt.page, page, next, want, tt.wantNext)
+			}
+		})
+	}
+}
+
+func TestSearchHandlerRejectsCursor(t *testing.T) {
+	for _, cursor := range []string{
+		"not-a-cursor!",
+		encodeCursor(20) + "!",
+		base64.RawURLEncoding.EncodeToString([]byte("-1")),
+		encodeCursor(1000), // well formed, past the results
+	} {
+		w := httptest.NewRecorder()
+		SearchHandler(w, httptest.NewRequest(http.MethodGet, "/api/search?search=queen&cursor="+url.QueryEscape(cursor), nil))
+		var body map[string]string
+		if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+			t.Fatalf("SearchHandler(cursor=%q) body: %v", cursor, err)
+		}
+		if w.Code != http.StatusBadRequest || !strings.Contains(body["error"], "cursor") {
+			t.Errorf("SearchHandler(cursor=%q) = %d %v, want 400 about the cursor", cursor, w.Code, body)
+		}
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"groupie-tracker/services"
 	"groupie-tracker/api"
 	"groupie-tracker/handlers"
+	"groupie-tracker/search"
 	"log"
 	"net/http"
 	"os"
@@ -12,6 +13,11 @@ import (
 func main () {
 	// load the file instantly
 	services.InitGeoCache()
+	// Rebuild the search index and the filters whenever the data is (re)loaded
+	api.OnDataLoaded(func() {
+		search.Rebuild(api.All_Artists, services.GetRelationsByID)
+		services.RebuildFilters()
+	})
 	
 	api.SetLoadingStatus(true, false, false)
 	// Initialize the data structures
@@ -36,6 +42,13 @@ func main () {
 	mux.HandleFunc("/loading/", handlers.LoadingHandler)
 	mux.HandleFunc("/static/", handlers.ResourcesHandler)
 	mux.HandleFunc("/api/search", handlers.SearchHandler)
+	mux.HandleFunc("/feeds/", handlers.FeedHandler)
+	mux.HandleFunc("/concerts.geojson", handlers.ConcertsGeoJSONHandler)
+	mux.HandleFunc("/api/geocode/status", handlers.GeocodeStatusHandler)
+	mux.HandleFunc("/admin/geocode", handlers.GeocodeAdminHandler)
+	mux.HandleFunc("/admin/geocode/report", handlers.GeocodeReportHandler)
+	mux.HandleFunc("/admin/geocode/job", handlers.GeocodeJobHandler)
+	mux.HandleFunc("/admin/anomalies", handlers.AnomaliesHandler)
 
 	// Start the server
 	port := os.Getenv("PORT")
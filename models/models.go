@@ -1,5 +1,7 @@
 package models
 
+import "time"
+
 type Artists struct {
 	ID           int      `json:"id"`
 	Image        string   `json:"image"`
@@ -46,11 +48,171 @@ type ArtistDetails struct {
 	Locations Locations
 	Dates     Dates
 	Relations Relations
-	MapData   map[string]Coordinates
+	MapPoints []MapPoint
+	Stats     TourStats
+	Anomalies []TravelAnomaly
+	Tours     []Tour
+}
+
+// MapPoint is a concert location with the coordinates it was resolved to.
+type MapPoint struct {
+	Name string
+	Coordinates
 }
 
-// struct to store latitude and longitude
+// struct to store latitude and longitude, plus what the geocoder told us
+// about the place they belong to
 type Coordinates struct {
-	Lat string `json:"lat"`
-	Lon string `json:"lon"`
+	Lat         float64   `json:"lat"`
+	Lon         float64   `json:"lon"`
+	DisplayName string    `json:"displayName,omitempty"`
+	Class       string    `json:"class,omitempty"` // OSM class, e.g. "place" or "boundary"
+	Type        string    `json:"type,omitempty"`  // OSM type, e.g. "city" or "administrative"
+	Importance  float64   `json:"importance,omitempty"`
+	BoundingBox []float64 `json:"boundingBox,omitempty"` // south, north, west, east
+	PlaceType   PlaceType `json:"placeType,omitempty"`
+}
+
+// PlaceType tells how precise a set of coordinates is: a city, or only the
+// centroid of a state or country.
+type PlaceType string
+
+const (
+	PlaceCity    PlaceType = "city"
+	PlaceState   PlaceType = "state"
+	PlaceCountry PlaceType = "country"
+)
+
+// GeocodeIssue is a cached location whose coordinates look wrong, listed in
+// the admin report together with the alternatives that can replace them.
+type GeocodeIssue struct {
+	Location   string
+	Current    Coordinates
+	Source     string
+	Reason     string
+	Candidates []Coordinates
+}
+
+// GeocodeProgress is the state of the background geocoding job as shown by
+// the status API.
+type GeocodeProgress struct {
+	State      string    `json:"state"` // idle, running, done, cancelled or stopped
+	Done       int       `json:"done"`  // locations processed, including failures
+	Total      int       `json:"total"`
+	Failed     int       `json:"failed"`
+	Retrying   int       `json:"retrying"` // locations waiting in the retry queue
+	ETASeconds int       `json:"etaSeconds"`
+	StartedAt  time.Time `json:"startedAt"`
+}
+
+// GeocodeEntry is one location on the admin geocoding page.
+type GeocodeEntry struct {
+	Location    string
+	Coordinates Coordinates
+	Source      string
+	FetchedAt   time.Time
+	Missing     bool // never found, or not looked up yet
+	Suspicious  bool
+	Pinned      bool
+	Usage       int // number of artists playing there
+}
+
+// Concert is a single show of an artist, as listed in the relations data.
+// Date is the local calendar date at midnight UTC; TimeZone, once known,
+// is the IANA zone of the location that date is local to.
+type Concert struct {
+	Location string    `json:"location"`
+	Date     time.Time `json:"date"`
+	TimeZone string    `json:"timeZone,omitempty"`
+}
+
+// Hop is the trip between two consecutive concerts.
+type Hop struct {
+	From     string    `json:"from"`
+	To       string    `json:"to"`
+	FromDate time.Time `json:"fromDate"`
+	ToDate   time.Time `json:"toDate"`
+	Km       float64   `json:"km"`
+}
+
+// YearStats sums up the travel of one calendar year. Hops count towards the
+// year of the concert they arrive at.
+type YearStats struct {
+	Year     int     `json:"year"`
+	Concerts int     `json:"concerts"`
+	Km       float64 `json:"km"`
+}
+
+// TourStats are the travel statistics of an artist.
+type TourStats struct {
+	Concerts  int         `json:"concerts"`
+	TotalKm   float64     `json:"totalKm"`
+	AverageKm float64     `json:"averageKm"` // per hop between shows
+	Longest   *Hop        `json:"longestHop,omitempty"`
+	Years     []YearStats `json:"years"`
+	Hops      []Hop       `json:"hops"`
+	Unmapped  []string    `json:"unmapped,omitempty"` // locations without coordinates, left out of the hops
+}
+
+// TravelAnomaly flags a pair of concerts an artist couldn't have played as
+// listed, usually because of a typo in the upstream dates.
+type TravelAnomaly struct {
+	Kind     string    `json:"kind"` // "speed" or "double-booking"
+	From     string    `json:"from"`
+	To       string    `json:"to"`
+	FromDate time.Time `json:"fromDate"`
+	ToDate   time.Time `json:"toDate"`
+	Km       float64   `json:"km"`
+	Hint     string    `json:"hint,omitempty"`
+}
+
+// ArtistAnomalies groups the anomalies of one artist for the admin report.
+type ArtistAnomalies struct {
+	ArtistID   int
+	ArtistName string
+	Anomalies  []TravelAnomaly
+}
+
+// Leg is a run of concerts inside a tour without a long break.
+type Leg struct {
+	Number   int       `json:"number"` // 1 for the first leg of a tour
+	Start    time.Time `json:"start"`
+	End      time.Time `json:"end"`
+	Concerts []Concert `json:"concerts"`
+}
+
+// Tour is a group of legs separated from other tours by a long break.
+type Tour struct {
+	Number     int       `json:"number"` // 1 for the earliest tour
+	Start      time.Time `json:"start"`
+	End        time.Time `json:"end"`
+	Concerts   int       `json:"concerts"`
+	Km         float64   `json:"km"`
+	Continents []string  `json:"continents"`
+	Legs       []Leg     `json:"legs"`
+}
+
+// ArtistConcert is a concert together with the artist playing it, as listed
+// in calendar feeds covering several artists.
+type ArtistConcert struct {
+	ArtistID   int
+	ArtistName string
+	Concert
+}
+
+// ArtistFilter narrows the artists listed on the home page. Zero values
+// leave a criterion out; years are inclusive.
+type ArtistFilter struct {
+	CreationFrom, CreationTo int
+	AlbumFrom, AlbumTo       int      // years of the first album
+	Members                  []int    // member counts, any of them
+	Locations                []string // formatted locations, played at any of them
+}
+
+// FilterOptions are the values the home page filters offer.
+type FilterOptions struct {
+	CreationMin, CreationMax int
+	AlbumMin, AlbumMax       int
+	MemberCounts             []int
+	Locations                []string
 }
@@ -0,0 +1,227 @@
+package search
+
+import (
+	"strconv"
+	"strings"
+
+	"groupie-tracker/geo"
+)
+
+// Query parses query (see Parse) and runs it against the index. Errors are
+// *SyntaxError.
+func (ix *Index) Query(query string) ([]SearchResult, error) {
+	q, err := Parse(query)
+	if err != nil {
+		return nil, err
+	}
+	return ix.Run(q), nil
+}
+
+// Run returns the results of the query's clauses for the artists that
+// match all of them and none of the negated ones, restricted to the
+// categories it names, scored, sorted and without duplicates.
+func (ix *Index) Run(q Query) []SearchResult {
+	var resultsPerClause [][]SearchResult
+	excluded := make(map[int]bool)
+	categories := make(map[string]bool)
+	excludedCategories := make(map[string]bool)
+	for _, c := range q.Clauses {
+		switch {
+		case c.Field == "category" && c.Negate:
+			excludedCategories[c.Text] = true
+		case c.Field == "category":
+			categories[c.Text] = true
+		case c.Negate:
+			for _, r := range ix.clause(c) {
+				excluded[r.ID] = true
+			}
+		default:
+			resultsPerClause = append(resultsPerClause, ix.clause(c))
+		}
+	}
+	if len(resultsPerClause) == 0 {
+		return []SearchResult{}
+	}
+
+	// The clauses' results are filtered in place: they are this query's
+	// own, and only one clause's are used as they are
+	matched := resultsPerClause[0]
+	if len(resultsPerClause) > 1 {
+		matched = MatchResults(resultsPerClause)
+	}
+	results := matched[:0]
+	for i := range matched {
+		r := &matched[i]
+		if excluded[r.ID] || excludedCategories[r.Category] || len(categories) > 0 && !categories[r.Category] {
+			continue
+		}
+		if len(results) < i {
+			matched[len(results)] = *r
+		}
+		results = results[:len(results)+1]
+	}
+	ix.Weights.addCoverage(results, resultsPerClause)
+	SortResults(results)
+	results = RemoveDuplicates(results)
+	addHighlights(results, q.highlightTerms())
+	return results
+}
+
+// highlightTerms are the words and phrases of the query's clauses, in the
+// fields they match; numbers and ranges aren't highlighted.
+func (q Query) highlightTerms() []highlightTerm {
+	fields := map[string]string{
+		"": "", "artist": "name", "member": "member", "location": "location",
+		"country": "location", "date": "date", "album": "first_album",
+	}
+	var terms []highlightTerm
+	for _, c := range q.Clauses {
+		f, ok := fields[c.Field]
+		if ok && !c.Negate && c.Range == nil {
+			terms = append(terms, highlightTerm{field: f, text: c.Text})
+		}
+	}
+	return terms
+}
+
+// clause returns the results matching a clause, ignoring Negate.
+func (ix *Index) clause(c Clause) []SearchResult {
+	switch c.Field {
+	case "artist":
+		return ix.fieldText(c, fieldName)
+	case "member":
+		return ix.fieldText(c, fieldMember)
+	case "location":
+		return ix.fieldText(c, fieldConcertLocation)
+	case "date":
+		return ix.fieldText(c, fieldConcertDate)
+	case "album":
+		if c.Range != nil {
+			return ix.scan(fieldFirstAlbum, func(e entry) bool {
+				year, err := strconv.Atoi(e.text[strings.LastIndex(e.text, "-")+1:])
+				return err == nil && c.Range.Contains(year)
+			})
+		}
+		return ix.fieldText(c, fieldFirstAlbum)
+	case "year":
+		return ix.scan(fieldCreationDate, func(e entry) bool {
+			year, err := strconv.Atoi(e.text)
+			return err == nil && c.Range.Contains(year)
+		})
+	case "members":
+		results := []SearchResult{}
+		for _, a := range ix.artists {
+			if a.nameEntry >= 0 && c.Range.Contains(a.members) {
+				results = append(results, ix.exact(ix.entries[a.nameEntry]))
+			}
+		}
+		return results
+	case "country":
+		country := countryKey(c.Text)
+		return ix.scan(fieldConcertLocation, func(e entry) bool {
+			return e.phrase != "" && countryKey(countryOf(e.text)) == country
+		})
+	}
+	if c.Phrase {
+		return ix.phrase(c.Text, func(field) bool { return true })
+	}
+	return ix.SearchAll(c.Text)
+}
+
+// fieldText returns the matches of a clause's text in field f.
+func (ix *Index) fieldText(c Clause, f field) []SearchResult {
+	if c.Phrase {
+		return ix.phrase(c.Text, func(g field) bool { return g == f })
+	}
+	results := []SearchResult{}
+	for _, r := range ix.SearchAll(c.Text) {
+		if r.Field == f.String() {
+			results = append(results, r)
+		}
+	}
+	return results
+}
+
+// scan returns the entries of field f that match, as exact matches.
+func (ix *Index) scan(f field, match func(entry) bool) []SearchResult {
+	results := []SearchResult{}
+	for _, e := range ix.entries {
+		if e.field == f && match(e) {
+			results = append(results, ix.exact(e))
+		}
+	}
+	return results
+}
+
+func (ix *Index) exact(e entry) SearchResult {
+	r := e.result(MethodPrefix, e.phrase)
+	r.Score = ix.Weights.score(&e, termMatch{quality: qualityExact})
+	return r
+}
+
+// phrase returns the names, members and locations in the fields accepted
+// by inField whose words include those of text, in order.
+func (ix *Index) phrase(text string, inField func(field) bool) []SearchResult {
+	p := phraseText(text)
+	results := []SearchResult{}
+	if p == "" {
+		return results
+	}
+	for _, e := range ix.entries {
+		if e.phrase == "" || !inField(e.field) {
+			continue
+		}
+		// Phrases match from the start of a word.
+		at := strings.Index(" "+e.phrase, " "+p)
+		if at < 0 {
+			continue
+		}
+		m := termMatch{quality: qualityContains, offset: int32(at), length: int32(len(e.phrase))}
+		if at == 0 {
+			m.quality = qualityPrefix
+			if len(p) == len(e.phrase) {
+				m.quality = qualityExact
+			}
+		}
+		r := e.result(m.quality.method(), p)
+		r.Score = ix.Weights.score(&e, m)
+		results = append(results, r)
+	}
+	return results
+}
+
+// countryOf returns the country a location ends with: "UK" for
+// "London, UK", "usa" for "new_york-usa".
+func countryOf(loc string) string {
+	if i := strings.LastIndex(loc, ","); i >= 0 {
+		return strings.TrimSpace(loc[i+1:])
+	}
+	return loc[strings.LastIndex(loc, "-")+1:]
+}
+
+// countryKey identifies a country however it is written: by its ISO code
+// when the country table knows the name, so that "uk", "UK" and
+// "united_kingdom" share a key, and by the whole normalized name otherwise.
+// Keys are compared whole, so "uk" doesn't pick Ukraine.
+func countryKey(country string) string {
+	if c, ok := geo.LookupCountry(country); ok {
+		return c.Code
+	}
+	return normalize(country)
+}
+
+// isSeparator reports whether r splits the words of names, members and
+// locations.
+func isSeparator(r rune) bool {
+	switch r {
+	case '-', ',', '.', ' ', '_', ':':
+		return true
+	}
+	return false
+}
+
+// phraseText folds s and separates its words with single spaces, so that
+// "New York" matches "new_york-usa".
+func phraseText(s string) string {
+	return strings.Join(strings.FieldsFunc(fold(s), isSeparator), " ")
+}
@@ -0,0 +1,121 @@
+package search
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Facets break the results of a search down, so it can be refined: hits
+// per category, and the artists hit per country of their concerts, decade
+// of creation and number of members.
+type Facets struct {
+	Categories []FacetValue `json:"categories"`
+	Countries  []FacetValue `json:"countries"`
+	Decades    []FacetValue `json:"decades"`
+	Members    []FacetValue `json:"members"`
+}
+
+// FacetValue is one value of a facet, how many hits or artists have it and
+// the clause that narrows a search down to them.
+type FacetValue struct {
+	Value  string `json:"value"`
+	Count  int    `json:"count"`
+	Clause string `json:"clause"`
+}
+
+// WithoutCategories returns q without its category: clauses.
+func (q Query) WithoutCategories() Query {
+	var clauses []Clause
+	for _, c := range q.Clauses {
+		if c.Field != "category" {
+			clauses = append(clauses, c)
+		}
+	}
+	return Query{Clauses: clauses}
+}
+
+// Facets counts the hits in all per category, and the artists in results
+// per country, decade and number of members. Passing the results of the
+// query without its category: clauses as all (see WithoutCategories) gives
+// every category the count picking it would give. Values hit by nothing
+// are left out.
+func (ix *Index) Facets(all, results []SearchResult) Facets {
+	f := Facets{Categories: []FacetValue{}, Countries: []FacetValue{}, Decades: []FacetValue{}, Members: []FacetValue{}}
+	categories := make(map[string]int)
+	for _, r := range all {
+		categories[r.Category]++
+	}
+	for _, c := range Categories {
+		if n := categories[c]; n > 0 {
+			f.Categories = append(f.Categories, FacetValue{Value: c, Count: n, Clause: "category:" + c})
+		}
+	}
+
+	countries := make(map[string]int)
+	countryNames := make(map[string]string) // key to the first spelling seen
+	decades := make(map[int]int)
+	members := make(map[int]int)
+	seen := make(map[int]bool)
+	for _, r := range results {
+		i, ok := ix.byID[r.ID]
+		if !ok || seen[r.ID] {
+			continue
+		}
+		seen[r.ID] = true
+		a := ix.artists[i]
+		for i, c := range a.countries {
+			key := a.countryKeys[i]
+			if _, ok := countryNames[key]; !ok {
+				countryNames[key] = c
+			}
+			countries[key]++
+		}
+		if a.creation > 0 {
+			decades[a.creation/10*10]++
+		}
+		if a.members > 0 {
+			members[a.members]++
+		}
+	}
+	for key, n := range countries {
+		c := countryNames[key]
+		f.Countries = append(f.Countries, FacetValue{Value: c, Count: n, Clause: "country:" + quoteValue(c)})
+	}
+	// Most artists first, then by name
+	sort.Slice(f.Countries, func(i, j int) bool {
+		if f.Countries[i].Count != f.Countries[j].Count {
+			return f.Countries[i].Count > f.Countries[j].Count
+		}
+		return f.Countries[i].Value < f.Countries[j].Value
+	})
+	for _, d := range sortedKeys(decades) {
+		f.Decades = append(f.Decades, FacetValue{
+			Value:  strconv.Itoa(d) + "s",
+			Count:  decades[d],
+			Clause: "year:" + strconv.Itoa(d) + ".." + strconv.Itoa(d+9),
+		})
+	}
+	for _, m := range sortedKeys(members) {
+		n := strconv.Itoa(m)
+		f.Members = append(f.Members, FacetValue{Value: n, Count: members[m], Clause: "members:" + n})
+	}
+	return f
+}
+
+func sortedKeys(m map[int]int) []int {
+	keys := make([]int, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Ints(keys)
+	return keys
+}
+
+// quoteValue quotes a clause value of several words.
+func quoteValue(s string) string {
+	if strings.ContainsAny(s, " \t") {
+		return `"` + s + `"`
+	}
+	return s
+}
@@ -0,0 +1,107 @@
+package search
+
+import (
+	"reflect"
+	"testing"
+
+	"groupie-tracker/models"
+)
+
+func TestFacets(t *testing.T) {
+	ix := queryFixture()
+	q, err := Parse("john category:member")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := ix.Facets(ix.Run(q.WithoutCategories()), ix.Run(q))
+
+	want := Facets{
+		Categories: []FacetValue{
+			{Value: "member", Count: 2, Clause: "category:member"},
+		},
+		Countries: []FacetValue{
+			{Value: "uk", Count: 2, Clause: "country:uk"},
+			{Value: "japan", Count: 1, Clause: "country:japan"},
+			{Value: "usa", Count: 1, Clause: "country:usa"},
+		},
+		Decades: []FacetValue{
+			{Value: "1960s", Count: 1, Clause: "year:1960..1969"},
+			{Value: "1970s", Count: 1, Clause: "year:1970..1979"},
+		},
+		Members: []FacetValue{
+			{Value: "4", Count: 2, Clause: "members:4"},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Facets = %+v\nwant %+v", got, want)
+	}
+}
+
+func TestFacetsCountCategoriesWithoutCategoryClauses(t *testing.T) {
+	ix := queryFixture()
+	q, err := Parse("19 category:concert")
+	if err != nil {
+		t.Fatal(err)
+	}
+	results := ix.Run(q)
+	got := ix.Facets(ix.Run(q.WithoutCategories()), results).Categories
+	counts := make(map[string]int)
+	for _, v := range got {
+		counts[v.Value] = v.Count
+	}
+	if counts["concert"] != len(results) {
+		t.Errorf("concert count = %d, want %d", counts["concert"], len(results))
+	}
+	if counts["first_album"] == 0 || counts["creation_date"] == 0 {
+		t.Errorf("Categories = %+v, want the other categories 19 hits too", got)
+	}
+}
+
+// The clause of a facet value narrows the search down to the artists it
+// counts.
+func TestFacetClausesRefine(t *testing.T) {
+	artists := []models.Artists{
+		{ID: 1, Name: "Santana", Members: []string{"Carlos Santana"}, CreationDate: 1966},
+		{ID: 2, Name: "Queen", Members: []string{"Freddie Mercury"}, CreationDate: 1970},
+		{ID: 3, Name: "Okean Elzy", Members: []string{"Svyatoslav Vakarchuk"}, CreationDate: 1994},
+		{ID: 4, Name: "Oasis", Members: []string{"Liam Gallagher"}, CreationDate: 1991},
+	}
+	relations := map[int]*models.Relations{
+		1: {SortedLocations: []string{"San Juan, Puerto Rico"}, DatesLocations: map[string][]string{"San Juan, Puerto Rico": {"01-01-2000"}}},
+		2: {SortedLocations: []string{"London, UK"}, DatesLocations: map[string][]string{"London, UK": {"01-01-1980"}}},
+		3: {SortedLocations: []string{"Kyiv, Ukraine"}, DatesLocations: map[string][]string{"Kyiv, Ukraine": {"01-01-2010"}}},
+		4: {SortedLocations: []string{"Manchester, United Kingdom", "Glasgow, UK"}, DatesLocations: map[string][]string{"Manchester, United Kingdom": {"01-01-1995"}, "Glasgow, UK": {"02-01-1995"}}},
+	}
+	ix := NewIndex(artists, func(id int) (*models.Relations, error) { return relations[id], nil })
+
+	all, err := ix.Query("members:1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	facets := ix.Facets(all, all)
+	for _, values := range [][]FacetValue{facets.Countries, facets.Decades} {
+		for _, v := range values {
+			refined, err := ix.Query("members:1 " + v.Clause)
+			if err != nil {
+				t.Errorf("refining with %s: %v", v.Clause, err)
+				continue
+			}
+			ids := make(map[int]bool)
+			for _, r := range refined {
+				ids[r.ID] = true
+			}
+			if len(ids) != v.Count {
+				t.Errorf("refining with %s hits %d artists, want %d", v.Clause, len(ids), v.Count)
+			}
+		}
+	}
+	clauses := make(map[string]int)
+	for _, v := range facets.Countries {
+		clauses[v.Clause] = v.Count
+	}
+	// "United Kingdom" and "UK" are one country, and neither is Ukraine.
+	want := map[string]int{`country:"Puerto Rico"`: 1, "country:UK": 2, "country:Ukraine": 1}
+	if !reflect.DeepEqual(clauses, want) {
+		t.Errorf("Countries = %+v, want clauses %v", facets.Countries, want)
+	}
+}
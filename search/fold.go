@@ -0,0 +1,9 @@
+package search
+
+import "groupie-tracker/textfold"
+
+// fold lowercases s and strips its diacritics, so that "Motörhead" and
+// "motorhead" compare equal. See textfold.String.
+func fold(s string) string {
+	return textfold.String(s)
+}
@@ -0,0 +1,38 @@
+package search
+
+import (
+	"testing"
+
+	"groupie-tracker/models"
+)
+
+func TestSearchFoldsDiacritics(t *testing.T) {
+	artists := []models.Artists{
+		{ID: 1, Name: "Motörhead", Members: []string{"Lemmy Kilmister"}, CreationDate: 1975, FirstAlbum: "21-08-1977"},
+		{ID: 2, Name: "Beyonce", Members: []string{"Beyoncé Knowles"}, CreationDate: 1997, FirstAlbum: "24-06-2003"},
+	}
+	getRelations := func(id int) (*models.Relations, error) {
+		return &models.Relations{
+			SortedLocations: []string{"zürich-switzerland"},
+			DatesLocations:  map[string][]string{"zürich-switzerland": {"10-10-2019"}},
+		}, nil
+	}
+	ix := NewIndex(artists, getRelations)
+
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"Motorhead", "Motörhead - Artist/Band"},
+		{"MOTÖRHEAD", "Motörhead - Artist/Band"},
+		{"beyoncé", "Beyonce - Artist/Band"},
+		{"knowles", "Knowles Beyoncé - Member of Beyonce"},
+		{"Zurich", "zürich-switzerland - Concert location on 10-10-2019 for Motörhead"},
+	}
+	for _, tt := range tests {
+		got := ix.Search(tt.query)
+		if len(got) == 0 || got[0].Label != tt.want || got[0].Method != MethodPrefix {
+			t.Errorf("Search(%q) = %v, want %q first as a prefix match", tt.query, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package search
+
+import "strings"
+
+// maxEdits is the number of typos tolerated in a word of n letters. Short
+// words get none: one edit away from "may" is half the dictionary.
+func maxEdits(n int) int {
+	switch {
+	case n < 4:
+		return 0
+	case n < 8:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// fuzzyWords splits s into the words matched fuzzily, normalized like
+// queries are.
+func fuzzyWords(s string) []string {
+	return strings.Fields(phraseText(s))
+}
+
+// editDistance returns the Damerau-Levenshtein distance between a and b
+// (in its optimal string alignment form: insertions, deletions,
+// substitutions and swaps of adjacent letters), or max+1 as soon as it
+// is known to be more than max.
+func editDistance(a, b []rune, max int) int {
+	if d := len(a) - len(b); d > max || -d > max {
+		return max + 1
+	}
+	// Three rows of the matrix: two back for swaps, the previous and this one.
+	prev2 := make([]int, len(b)+1)
+	prev := make([]int, len(b)+1)
+	row := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		row[0] = i
+		best := row[0]
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			d := prev[j-1] + cost
+			if del := prev[j] + 1; del < d {
+				d = del
+			}
+			if ins := row[j-1] + 1; ins < d {
+				d = ins
+			}
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				if swap := prev2[j-2] + 1; swap < d {
+					d = swap
+				}
+			}
+			row[j] = d
+			if d < best {
+				best = d
+			}
+		}
+		if best > max {
+			return max + 1
+		}
+		prev2, prev, row = prev, row, prev2
+	}
+	if d := prev[len(b)]; d <= max {
+		return d
+	}
+	return max + 1
+}
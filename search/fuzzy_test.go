@@ -0,0 +1,85 @@
+package search
+
+import (
+	"testing"
+
+	"groupie-tracker/models"
+)
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		max  int
+		want int
+	}{
+		{"metallica", "metallica", 2, 0},
+		{"metalica", "metallica", 2, 1}, // deletion
+		{"beatels", "beatles", 1, 1},    // swap
+		{"mercuri", "mercury", 1, 1},    // substitution
+		{"zurih", "zürich", 2, 2},       // runes, not bytes
+		{"abcdef", "badcfe", 3, 3},      // three swaps
+		{"queen", "scorpions", 2, 3},    // capped at max+1
+		{"ab", "abcdefgh", 2, 3},        // length alone rules it out
+		{"", "abc", 3, 3},
+	}
+	for _, tt := range tests {
+		if got := editDistance([]rune(tt.a), []rune(tt.b), tt.max); got != tt.want {
+			t.Errorf("editDistance(%q, %q, %d) = %d, want %d", tt.a, tt.b, tt.max, got, tt.want)
+		}
+	}
+}
+
+func TestFuzzySearch(t *testing.T) {
+	artists := []models.Artists{
+		{ID: 1, Name: "Queen", Members: []string{"Freddie Mercury"}, CreationDate: 1970, FirstAlbum: "14-12-1973"},
+		{ID: 2, Name: "Metallica", Members: []string{"James Hetfield"}, CreationDate: 1981, FirstAlbum: "25-07-1983"},
+		{ID: 3, Name: "The Beatles", Members: []string{"John Lennon"}, CreationDate: 1960, FirstAlbum: "22-03-1963"},
+		{ID: 4, Name: "Mercury Rev", Members: []string{"Jonathan Donahue"}, CreationDate: 1989, FirstAlbum: "01-01-1991"},
+	}
+	getRelations := func(id int) (*models.Relations, error) {
+		return &models.Relations{
+			SortedLocations: []string{"los_angeles-usa"},
+			DatesLocations:  map[string][]string{"los_angeles-usa": {"01-01-2020"}},
+		}, nil
+	}
+	ix := NewIndex(artists, getRelations)
+
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"metalica", "Metallica - Artist/Band"},
+		{"beatels", "The Beatles - Artist/Band"},
+		{"hetfeild", "James Hetfield - Member of Metallica"},
+		{"angelse", "los_angeles-usa - Concert location on 01-01-2020 for Queen"},
+	}
+	for _, tt := range tests {
+		got := ix.Search(tt.query)
+		if len(got) == 0 || got[0].Label != tt.want || got[0].Method != MethodFuzzy {
+			t.Errorf("Search(%q) = %v, want %q first as a fuzzy match", tt.query, got, tt.want)
+		}
+	}
+
+	// Exact matches come before fuzzy ones.
+	got := ix.Search("Mercuri")
+	if len(got) != 2 {
+		t.Fatalf("Search(Mercuri) = %v, want 2 results", got)
+	}
+	if got[0].Method != MethodFuzzy || got[1].Method != MethodFuzzy {
+		t.Errorf("Search(Mercuri) = %v, want fuzzy matches", got)
+	}
+	got = ix.Search("mercur")
+	if len(got) == 0 || got[0].Method != MethodPrefix {
+		t.Errorf("Search(mercur) = %v, want prefix matches first", got)
+	}
+	for i := 1; i < len(got); i++ {
+		if got[i].Method > got[i-1].Method {
+			t.Errorf("Search(mercur) = %v, not ordered by method", got)
+		}
+	}
+
+	// Short words need to be spelled right.
+	if got := ix.Search("qen"); len(got) != 0 {
+		t.Errorf("Search(qen) = %v, want no results", got)
+	}
+}
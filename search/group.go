@@ -0,0 +1,54 @@
+package search
+
+// ArtistGroup is the results of a search for one artist: how many there
+// are, the best score among them and the best few, as the reasons the
+// artist matched.
+type ArtistGroup struct {
+	ID      int            `json:"id"`
+	Artist  string         `json:"artist"`
+	Hits    int            `json:"hits"`
+	Score   float64        `json:"score"`
+	Matches []SearchResult `json:"matches"`
+}
+
+// Group gathers sorted results by artist, keeping up to reasons matches
+// for each. Artists come in the order of their best result.
+func (ix *Index) Group(results []SearchResult, reasons int) []ArtistGroup {
+	groups := []ArtistGroup{}
+	position := make(map[int]int)
+	for _, r := range results {
+		i, ok := position[r.ID]
+		if !ok {
+			i = len(groups)
+			position[r.ID] = i
+			g := ArtistGroup{ID: r.ID, Score: r.Score, Matches: []SearchResult{}}
+			if a, ok := ix.byID[r.ID]; ok {
+				g.Artist = ix.artists[a].name
+			}
+			groups = append(groups, g)
+		}
+		g := &groups[i]
+		g.Hits++
+		if r.Score > g.Score {
+			g.Score = r.Score
+		}
+		if len(g.Matches) < reasons {
+			g.Matches = append(g.Matches, r)
+		}
+	}
+	return groups
+}
+
+// CapCategories keeps the first max results of each category, so that one
+// category, such as the concerts of a year, can't crowd out the others.
+func CapCategories(results []SearchResult, max int) []SearchResult {
+	kept := []SearchResult{}
+	counts := make(map[string]int)
+	for _, r := range results {
+		if counts[r.Category] < max {
+			counts[r.Category]++
+			kept = append(kept, r)
+		}
+	}
+	return kept
+}
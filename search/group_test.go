@@ -0,0 +1,47 @@
+package search
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGroup(t *testing.T) {
+	ix := queryFixture()
+	results, err := ix.Query("john")
+	if err != nil {
+		t.Fatal(err)
+	}
+	results = append(results, SearchResult{ID: 1, Label: "extra", Category: "concert", Score: 0.5})
+	groups := ix.Group(results, 1)
+
+	var artists []string
+	for _, g := range groups {
+		artists = append(artists, g.Artist)
+		if len(g.Matches) != 1 || g.Matches[0].ID != g.ID {
+			t.Errorf("group %s has matches %+v, want its best one", g.Artist, g.Matches)
+		}
+		if g.Score != g.Matches[0].Score {
+			t.Errorf("group %s scores %v, want its best match's %v", g.Artist, g.Score, g.Matches[0].Score)
+		}
+	}
+	if want := []string{"Queen", "The Beatles"}; !reflect.DeepEqual(artists, want) {
+		t.Errorf("artists = %q, want %q", artists, want)
+	}
+	if groups[0].Hits != 2 || groups[1].Hits != 1 {
+		t.Errorf("hits = %d, %d, want 2, 1", groups[0].Hits, groups[1].Hits)
+	}
+}
+
+func TestCapCategories(t *testing.T) {
+	results := []SearchResult{
+		{Label: "a", Category: "concert"},
+		{Label: "b", Category: "concert"},
+		{Label: "c", Category: "artist"},
+		{Label: "d", Category: "concert"},
+		{Label: "e", Category: "member"},
+	}
+	got := labels(CapCategories(results, 2))
+	if want := []string{"a", "b", "c", "e"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("CapCategories = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,216 @@
+package search
+
+import (
+	"bytes"
+	"strings"
+)
+
+// Span is a matched part of a result's Value. Start and End are byte
+// offsets, RuneStart and RuneEnd the same in runes, for clients that
+// don't index strings by byte.
+type Span struct {
+	Start, End         int
+	RuneStart, RuneEnd int
+}
+
+// LabelPart is a piece of a result's label, highlighted if Match.
+type LabelPart struct {
+	Text  string
+	Match bool
+}
+
+// LabelParts splits a result for display: its Value with the highlighted
+// spans apart, then the rest of its label, as in " - Member of Queen".
+// A result without a value is its label alone.
+func (r SearchResult) LabelParts() []LabelPart {
+	rest := strings.Index(r.Label, " - ")
+	if r.Value == "" || rest < 0 {
+		return []LabelPart{{Text: r.Label}}
+	}
+	var parts []LabelPart
+	at := 0
+	for _, s := range r.Highlights {
+		if s.Start > at {
+			parts = append(parts, LabelPart{Text: r.Value[at:s.Start]})
+		}
+		parts = append(parts, LabelPart{Text: r.Value[s.Start:s.End], Match: true})
+		at = s.End
+	}
+	if at < len(r.Value) {
+		parts = append(parts, LabelPart{Text: r.Value[at:]})
+	}
+	return append(parts, LabelPart{Text: r.Label[rest:]})
+}
+
+// highlightTerm is a word or phrase of a query to highlight in the
+// results of a field, or of any field if field is "".
+type highlightTerm struct {
+	field string
+	text  string
+}
+
+// addHighlights sets the spans of each result's Value that the terms match.
+// Results often follow one with the same value, such as a location with
+// several dates, whose spans are then reused.
+func addHighlights(results []SearchResult, terms []highlightTerm) {
+	keys := make([]string, len(terms))
+	for i, t := range terms {
+		keys[i] = normalize(t.text)
+	}
+	var h highlighter
+	var matching []string
+	for i := range results {
+		r := &results[i]
+		if i > 0 {
+			if p := &results[i-1]; p.Value == r.Value && p.Field == r.Field && p.Method == r.Method {
+				r.Highlights = p.Highlights
+				continue
+			}
+		}
+		matching = matching[:0]
+		for j, t := range terms {
+			if t.field == "" || t.field == r.Field {
+				matching = append(matching, keys[j])
+			}
+		}
+		r.Highlights = h.spans(r.Value, matching, r.Method == MethodFuzzy)
+	}
+}
+
+// highlight returns the spans of value that terms, normalized as queries
+// are, match in value once normalized too, in order and without overlaps.
+// For a fuzzy match, a term that isn't found highlights the words within a
+// few typos of it instead.
+func highlight(value string, terms []string, fuzzy bool) []Span {
+	var h highlighter
+	return h.spans(value, terms, fuzzy)
+}
+
+// highlighter keeps the buffers of highlight between calls.
+type highlighter struct {
+	key             []byte
+	origin, offsets []int
+	found           []runeSpan
+	slab            []Span // where the spans returned are allocated from
+}
+
+// spans is highlight, reusing h's buffers.
+func (h *highlighter) spans(value string, terms []string, fuzzy bool) []Span {
+	if value == "" || len(terms) == 0 {
+		return nil
+	}
+	// key is value normalized; origin[i] is the rune of value that key[i]
+	// comes from, and offsets[r] the byte offset of rune r.
+	key, origin, offsets := h.key[:0], h.origin[:0], h.offsets[:0]
+	defer func() { h.key, h.origin, h.offsets = key, origin, offsets }()
+	for offset, r := range value {
+		switch {
+		case isSeparator(r):
+		case r < 0x80:
+			if 'A' <= r && r <= 'Z' {
+				r += 'a' - 'A'
+			}
+			key = append(key, byte(r))
+			origin = append(origin, len(offsets))
+		default:
+			for _, b := range []byte(fold(string(r))) {
+				key = append(key, b)
+				origin = append(origin, len(offsets))
+			}
+		}
+		offsets = append(offsets, offset)
+	}
+	offsets = append(offsets, len(value))
+
+	found := h.found[:0]
+	defer func() { h.found = found }()
+	for _, t := range terms {
+		if t == "" {
+			continue
+		}
+		matched := false
+		for from := 0; from+len(t) <= len(key); {
+			i := bytes.Index(key[from:], []byte(t))
+			if i < 0 {
+				break
+			}
+			i += from
+			found = append(found, runeSpan{origin[i], origin[i+len(t)-1] + 1})
+			matched = true
+			from = i + len(t)
+		}
+		if matched || !fuzzy {
+			continue
+		}
+		query := []rune(t)
+		max := maxEdits(len(query))
+		if max == 0 {
+			continue
+		}
+		for _, w := range wordSpans(value) {
+			word := []rune(normalize(value[offsets[w.start]:offsets[w.end]]))
+			if editDistance(query, word, max) <= max {
+				found = append(found, runeSpan{w.start, w.end})
+			}
+		}
+	}
+	if len(found) == 0 {
+		return nil
+	}
+
+	// found is short: an insertion sort spares sort.Slice's allocations
+	for i := 1; i < len(found); i++ {
+		for j := i; j > 0 && found[j].start < found[j-1].start; j-- {
+			found[j], found[j-1] = found[j-1], found[j]
+		}
+	}
+	merged := found[:1]
+	for _, s := range found[1:] {
+		last := &merged[len(merged)-1]
+		if s.start <= last.end {
+			if s.end > last.end {
+				last.end = s.end
+			}
+			continue
+		}
+		merged = append(merged, s)
+	}
+	if cap(h.slab)-len(h.slab) < len(merged) {
+		size := 2 * cap(h.slab)
+		if size < len(merged) {
+			size = len(merged)
+		}
+		h.slab = make([]Span, 0, size)
+	}
+	start := len(h.slab)
+	for _, s := range merged {
+		h.slab = append(h.slab, Span{Start: offsets[s.start], End: offsets[s.end], RuneStart: s.start, RuneEnd: s.end})
+	}
+	return h.slab[start:len(h.slab):len(h.slab)]
+}
+
+// runeSpan is a span of a string in runes.
+type runeSpan struct{ start, end int }
+
+// wordSpans returns where the words of s are, split as phraseText splits
+// them.
+func wordSpans(s string) []runeSpan {
+	var words []runeSpan
+	start := -1
+	n := 0
+	for _, r := range s {
+		if isSeparator(r) {
+			if start >= 0 {
+				words = append(words, runeSpan{start, n})
+				start = -1
+			}
+		} else if start < 0 {
+			start = n
+		}
+		n++
+	}
+	if start >= 0 {
+		words = append(words, runeSpan{start, n})
+	}
+	return words
+}
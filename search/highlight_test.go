@@ -0,0 +1,83 @@
+package search
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestHighlight(t *testing.T) {
+	tests := []struct {
+		value string
+		terms []string
+		fuzzy bool
+		want  []string // the highlighted text
+	}{
+		{"Freddie Mercury", []string{"merc"}, false, []string{"Merc"}},
+		{"Freddie Mercury", []string{"freddie", "mercury"}, false, []string{"Freddie", "Mercury"}},
+		{"Motörhead", []string{"motorhead"}, false, []string{"Motörhead"}},
+		{"Zürich, Switzerland", []string{"zur"}, false, []string{"Zür"}},
+		{"Straße", []string{"strasse"}, false, []string{"Straße"}},
+		{"Straße", []string{"ss"}, false, []string{"ß"}},
+		{"new_york-usa", []string{"new york"}, false, []string{"new_york"}},
+		{"New York, USA", []string{"newyork"}, false, []string{"New York"}},
+		{"Metallica", []string{"metalica"}, true, []string{"Metallica"}},
+		{"Metallica", []string{"metalica"}, false, nil},
+		{"Queen", []string{"q", "queen"}, false, []string{"Queen"}},
+		{"28-01-2020", []string{"01-2020"}, false, []string{"01-2020"}},
+		{"Queen", []string{"abba"}, false, nil},
+	}
+	for _, tt := range tests {
+		var got []string
+		keys := make([]string, len(tt.terms))
+		for i, term := range tt.terms {
+			keys[i] = normalize(term)
+		}
+		for _, s := range highlight(tt.value, keys, tt.fuzzy) {
+			runes := []rune(tt.value)
+			if string(runes[s.RuneStart:s.RuneEnd]) != tt.value[s.Start:s.End] {
+				t.Errorf("highlight(%q, %q): span %+v has different byte and rune text", tt.value, tt.terms, s)
+			}
+			got = append(got, tt.value[s.Start:s.End])
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("highlight(%q, %q) = %q, want %q", tt.value, tt.terms, got, tt.want)
+		}
+	}
+}
+
+func TestQueryHighlights(t *testing.T) {
+	ix := queryFixture()
+	results, err := ix.Query(`member:john "new york"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, r := range results {
+		for _, p := range r.LabelParts() {
+			if p.Match {
+				got = append(got, r.Field+":"+p.Text)
+			}
+		}
+	}
+	// john is only highlighted in members, the phrase in locations
+	want := []string{"member:John", "location:new_york"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("highlighted %q, want %q", got, want)
+	}
+}
+
+func TestLabelParts(t *testing.T) {
+	r := SearchResult{
+		Label:      "Mercury Freddie - Member of Queen",
+		Value:      "Freddie Mercury",
+		Highlights: []Span{{Start: 8, End: 12, RuneStart: 8, RuneEnd: 12}},
+	}
+	want := []LabelPart{{Text: "Freddie "}, {Text: "Merc", Match: true}, {Text: "ury"}, {Text: " - Member of Queen"}}
+	if got := r.LabelParts(); !reflect.DeepEqual(got, want) {
+		t.Errorf("LabelParts = %+v, want %+v", got, want)
+	}
+	plain := SearchResult{Label: "Queen - Artist/Band"}
+	if got := plain.LabelParts(); !reflect.DeepEqual(got, []LabelPart{{Text: "Queen - Artist/Band"}}) {
+		t.Errorf("LabelParts without a value = %+v", got)
+	}
+}
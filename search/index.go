@@ -0,0 +1,476 @@
+package search
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"groupie-tracker/models"
+)
+
+// field is the part of an artist an index entry was taken from.
+type field int
+
+const (
+	fieldName field = iota
+	fieldMember
+	fieldFirstAlbum
+	fieldCreationDate
+	fieldConcertDate
+	fieldConcertLocation
+)
+
+// String returns the name results give the field.
+func (f field) String() string {
+	switch f {
+	case fieldName:
+		return "name"
+	case fieldMember:
+		return "member"
+	case fieldFirstAlbum:
+		return "first_album"
+	case fieldCreationDate:
+		return "creation_date"
+	case fieldConcertDate:
+		return "date"
+	}
+	return "location"
+}
+
+// category returns the category of the results on the field.
+func (f field) category() string {
+	switch f {
+	case fieldName:
+		return "artist"
+	case fieldMember:
+		return "member"
+	case fieldFirstAlbum:
+		return "first_album"
+	case fieldCreationDate:
+		return "creation_date"
+	}
+	return "concert"
+}
+
+// entry is one place a term occurs, with what is needed to label a result.
+// Entries are stored in the order a full scan of the artists meets them,
+// so results keep that order.
+type entry struct {
+	field  field
+	id     int
+	artist string
+	text   string // the member, album, creation date, concert date or location
+	other  string // the location of a concert date, or the date of a location
+	word   int    // position of the term among the words of a name or member
+	phrase string // the folded words of a name, member or location, on one entry of each
+	label  string // the label of its results; see result
+}
+
+// artistInfo is what clauses and facets about a whole artist need.
+type artistInfo struct {
+	id          int
+	name        string
+	members     int
+	creation    int
+	countries   []string // the countries of its concerts, each once (see countryKey)
+	countryKeys []string // the countryKey of each of countries
+	nameEntry   int32    // an entry for the artist's name, -1 if it has none
+}
+
+// term is a distinct indexed string and the entries it occurs in.
+type term struct {
+	text     string
+	postings []int32
+}
+
+// suffix points at a byte offset in a term. Sorted suffixes find every
+// term that contains a query, and those at offset 0 are prefix matches.
+type suffix struct {
+	term   int32
+	offset int32
+}
+
+// terms is a set of terms with their suffixes sorted.
+type terms struct {
+	ids      map[string]int32
+	list     []term
+	suffixes []suffix
+	// byLength holds the terms of each length in runes, as runes, for
+	// fuzzy matching: a term can only be within n typos of a query whose
+	// length differs by at most n.
+	byLength [][]lengthTerm
+}
+
+// lengthTerm is a term of byLength.
+type lengthTerm struct {
+	id    int32
+	runes []rune
+}
+
+// Index is an inverted index over the artists. Build one with NewIndex;
+// it is read-only afterwards and safe for concurrent use.
+type Index struct {
+	entries []entry
+	artists []artistInfo
+	byID    map[int]int // artist ID to its position in artists
+	// plain holds folded words; locations are matched against their
+	// normalized form, kept apart since queries are normalized for them.
+	plain, locations terms
+	// fuzzy holds the words of names, members and locations, normalized,
+	// to be matched within a few typos. It has no suffixes.
+	fuzzy terms
+	// Weights score the results; NewIndex sets them from $SEARCH_WEIGHTS.
+	Weights Weights
+}
+
+var current atomic.Pointer[Index]
+
+// Rebuild indexes artists and makes the index the one Current returns.
+// Searches running meanwhile keep using the previous index.
+func Rebuild(artists []models.Artists, getRelations func(int) (*models.Relations, error)) {
+	current.Store(NewIndex(artists, getRelations))
+}
+
+// Current returns the latest index built by Rebuild, or an empty index.
+func Current() *Index {
+	if ix := current.Load(); ix != nil {
+		return ix
+	}
+	return &Index{}
+}
+
+// NewIndex indexes the artists by name, members, first album, creation
+// date, concert dates and locations. Artists whose relations can't be
+// fetched are indexed without them.
+func NewIndex(artists []models.Artists, getRelations func(int) (*models.Relations, error)) *Index {
+	ix := &Index{Weights: weights, byID: make(map[int]int, len(artists))}
+	for _, artist := range artists {
+		info := artistInfo{id: artist.ID, name: artist.Name, members: len(artist.Members), creation: artist.CreationDate, nameEntry: -1}
+		for i, part := range strings.Fields(fold(artist.Name)) {
+			e := ix.add(&ix.plain, part, entry{field: fieldName, id: artist.ID, artist: artist.Name, word: i})
+			ix.addFuzzy(part, e)
+			if i == 0 {
+				info.nameEntry = e
+				ix.entries[e].phrase = phraseText(artist.Name)
+			}
+		}
+		ix.byID[artist.ID] = len(ix.artists)
+		ix.artists = append(ix.artists, info)
+		for _, member := range artist.Members {
+			for i, part := range strings.Fields(fold(member)) {
+				e := ix.add(&ix.plain, part, entry{field: fieldMember, id: artist.ID, artist: artist.Name, text: member, word: i})
+				ix.addFuzzy(part, e)
+				if i == 0 {
+					ix.entries[e].phrase = phraseText(member)
+				}
+			}
+		}
+		ix.add(&ix.plain, fold(artist.FirstAlbum), entry{field: fieldFirstAlbum, id: artist.ID, artist: artist.Name, text: artist.FirstAlbum})
+		creationDate := strconv.Itoa(artist.CreationDate)
+		ix.add(&ix.plain, creationDate, entry{field: fieldCreationDate, id: artist.ID, artist: artist.Name, text: creationDate})
+
+		rel, err := getRelations(artist.ID)
+		if err != nil {
+			continue
+		}
+		a := &ix.artists[ix.byID[artist.ID]]
+		seen := make(map[string]bool)
+		for _, loc := range rel.SortedLocations {
+			if country := countryOf(loc); country != "" {
+				if key := countryKey(country); !seen[key] {
+					seen[key] = true
+					a.countries = append(a.countries, country)
+					a.countryKeys = append(a.countryKeys, key)
+				}
+			}
+			for _, date := range rel.DatesLocations[loc] {
+				ix.add(&ix.plain, fold(date), entry{field: fieldConcertDate, id: artist.ID, artist: artist.Name, text: date, other: loc})
+				for i, part := range strings.Fields(normalize(loc)) {
+					e := ix.add(&ix.locations, part, entry{field: fieldConcertLocation, id: artist.ID, artist: artist.Name, text: loc, other: date})
+					ix.addFuzzy(loc, e)
+					if i == 0 {
+						ix.entries[e].phrase = phraseText(loc)
+					}
+				}
+			}
+		}
+	}
+	ix.plain.sortSuffixes()
+	ix.locations.sortSuffixes()
+	ix.fuzzy.groupByLength()
+	return ix
+}
+
+// add stores e, records that text occurs in it and returns its number.
+func (ix *Index) add(ts *terms, text string, e entry) int32 {
+	e.label = e.labelFor(e.text)
+	ix.entries = append(ix.entries, e)
+	n := int32(len(ix.entries) - 1)
+	ts.add(text, n, true)
+	return n
+}
+
+// addFuzzy records the words of text as occurring in entry e.
+func (ix *Index) addFuzzy(text string, e int32) {
+	for _, w := range fuzzyWords(text) {
+		if maxEdits(len([]rune(w))) > 0 {
+			ix.fuzzy.add(w, e, false)
+		}
+	}
+}
+
+// add records that text occurs in entry e, indexing the suffixes of text
+// if it is new and withSuffixes is set.
+func (ts *terms) add(text string, e int32, withSuffixes bool) {
+	id, ok := ts.ids[text]
+	if !ok {
+		if ts.ids == nil {
+			ts.ids = map[string]int32{}
+		}
+		id = int32(len(ts.list))
+		ts.ids[text] = id
+		ts.list = append(ts.list, term{text: text})
+		for offset := 0; withSuffixes && offset <= len(text); offset++ {
+			ts.suffixes = append(ts.suffixes, suffix{term: id, offset: int32(offset)})
+		}
+	}
+	if p := ts.list[id].postings; len(p) == 0 || p[len(p)-1] != e {
+		ts.list[id].postings = append(p, e)
+	}
+}
+
+func (ts *terms) suffix(s suffix) string {
+	return ts.list[s.term].text[s.offset:]
+}
+
+func (ts *terms) groupByLength() {
+	for id, t := range ts.list {
+		runes := []rune(t.text)
+		for len(ts.byLength) <= len(runes) {
+			ts.byLength = append(ts.byLength, nil)
+		}
+		ts.byLength[len(runes)] = append(ts.byLength[len(runes)], lengthTerm{int32(id), runes})
+	}
+}
+
+// near calls f with the terms whose length in runes is within max of n.
+func (ts *terms) near(n, max int, f func(lengthTerm)) {
+	for l := n - max; l <= n+max; l++ {
+		if l < 0 || l >= len(ts.byLength) {
+			continue
+		}
+		for _, t := range ts.byLength[l] {
+			f(t)
+		}
+	}
+}
+
+func (ts *terms) sortSuffixes() {
+	sort.Slice(ts.suffixes, func(i, j int) bool {
+		return ts.suffix(ts.suffixes[i]) < ts.suffix(ts.suffixes[j])
+	})
+}
+
+// termMatch is how a term matched a query: for a contains match, offset
+// is where the query first occurs in the term, of the given length.
+type termMatch struct {
+	quality        quality
+	offset, length int32
+}
+
+// match returns how each term containing q matches it.
+func (ts *terms) match(q string) map[int32]termMatch {
+	i := sort.Search(len(ts.suffixes), func(i int) bool {
+		return ts.suffix(ts.suffixes[i]) >= q
+	})
+	// the suffixes starting with q follow each other from i to end
+	end := i + sort.Search(len(ts.suffixes)-i, func(j int) bool {
+		return !strings.HasPrefix(ts.suffix(ts.suffixes[i+j]), q)
+	})
+	found := make(map[int32]termMatch, end-i)
+	for ; i < end; i++ {
+		s := ts.suffixes[i]
+		length := int32(len(ts.list[s.term].text))
+		m, seen := found[s.term]
+		switch {
+		case s.offset == 0 && int(length) == len(q):
+			found[s.term] = termMatch{quality: qualityExact, length: length}
+		case s.offset == 0:
+			found[s.term] = termMatch{quality: qualityPrefix, length: length}
+		case !seen || m.quality == qualityContains && s.offset < m.offset:
+			found[s.term] = termMatch{quality: qualityContains, offset: s.offset, length: length}
+		}
+	}
+	return found
+}
+
+// fuzzyMatch returns the terms within maxEdits typos of q.
+func (ts *terms) fuzzyMatch(q string) []int32 {
+	query := []rune(q)
+	max := maxEdits(len(query))
+	if max == 0 {
+		return nil
+	}
+	var found []int32
+	ts.near(len(query), max, func(t lengthTerm) {
+		if editDistance(query, t.runes, max) <= max {
+			found = append(found, t.id)
+		}
+	})
+	return found
+}
+
+// hit is an entry matching a query, and how.
+type hit struct {
+	entry int32
+	match termMatch
+}
+
+// SearchAll returns the results for a single-word query, in the same
+// order as a scan of the artists would find them. Names, members and
+// locations a few typos away from the query come back as fuzzy matches.
+func (ix *Index) SearchAll(query string) []SearchResult {
+	// Every entry has a single plain or location term, so those hits are
+	// distinct; fuzzy ones are added for the entries not hit already.
+	searchQuery := fold(query)
+	plain, locations := ix.plain.match(searchQuery), ix.locations.match(normalize(searchQuery))
+	found := []struct {
+		ts      *terms
+		matches map[int32]termMatch
+	}{{&ix.plain, plain}, {&ix.locations, locations}}
+	count := 0
+	for _, f := range found {
+		for id := range f.matches {
+			count += len(f.ts.list[id].postings)
+		}
+	}
+	hits := make([]hit, 0, count)
+	if count*8 < len(ix.entries) {
+		for _, f := range found {
+			for id, m := range f.matches {
+				for _, e := range f.ts.list[id].postings {
+					hits = append(hits, hit{e, m})
+				}
+			}
+		}
+		sortHits(hits)
+	} else {
+		// A broad query hits a good part of the entries: marking them and
+		// reading them back in order is cheaper than sorting.
+		marked := make([]termMatch, len(ix.entries))
+		for _, f := range found {
+			for id, m := range f.matches {
+				for _, e := range f.ts.list[id].postings {
+					marked[e] = m
+				}
+			}
+		}
+		for e, m := range marked {
+			if m.quality != 0 {
+				hits = append(hits, hit{int32(e), m})
+			}
+		}
+	}
+	if ids := ix.fuzzy.fuzzyMatch(normalize(searchQuery)); len(ids) > 0 {
+		var fuzzy []hit
+		for _, id := range ids {
+			for _, e := range ix.fuzzy.list[id].postings {
+				if !containsHit(hits, e) {
+					fuzzy = append(fuzzy, hit{e, termMatch{quality: qualityFuzzy}})
+				}
+			}
+		}
+		sortHits(fuzzy)
+		hits = mergeHits(hits, fuzzy)
+	}
+
+	results := make([]SearchResult, len(hits))
+	for i, h := range hits {
+		e := &ix.entries[h.entry]
+		results[i] = e.result(h.match.quality.method(), searchQuery)
+		results[i].Score = ix.Weights.score(e, h.match)
+	}
+	return results
+}
+
+// sortHits sorts hits by entry, which is the order of a scan.
+func sortHits(hits []hit) {
+	sort.Sort(byEntry(hits))
+}
+
+type byEntry []hit
+
+func (h byEntry) Len() int           { return len(h) }
+func (h byEntry) Less(i, j int) bool { return h[i].entry < h[j].entry }
+func (h byEntry) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+
+// containsHit reports whether the sorted hits include entry e.
+func containsHit(hits []hit, e int32) bool {
+	i := sort.Search(len(hits), func(i int) bool { return hits[i].entry >= e })
+	return i < len(hits) && hits[i].entry == e
+}
+
+// mergeHits merges two sorted lists of hits on different entries, dropping
+// repeated hits on an entry in fuzzy.
+func mergeHits(hits, fuzzy []hit) []hit {
+	merged := make([]hit, 0, len(hits)+len(fuzzy))
+	i := 0
+	for j, f := range fuzzy {
+		if j > 0 && fuzzy[j-1].entry == f.entry {
+			continue
+		}
+		for ; i < len(hits) && hits[i].entry < f.entry; i++ {
+			merged = append(merged, hits[i])
+		}
+		merged = append(merged, f)
+	}
+	return append(merged, hits[i:]...)
+}
+
+// result labels a match of query in e.
+func (e *entry) result(method SearchMethod, query string) SearchResult {
+	r := SearchResult{Label: e.label, ID: e.id, Category: e.field.category(), Method: method, Field: e.field.String(), Value: e.text}
+	switch e.field {
+	case fieldName:
+		r.Value = e.artist
+	case fieldMember:
+		if method == MethodPrefix {
+			// If match is on surname (not first word), reorder to surname first
+			parts := strings.Fields(e.text)
+			name := parts[0]
+			surname := parts[len(parts)-1]
+			if len(parts) > 1 && strings.HasPrefix(fold(surname), query) {
+				r.Label = e.labelFor(surname + " " + name)
+			}
+		}
+	}
+	return r
+}
+
+// labelFor is the label of e's results with text in place of e.text. The
+// labels are built with the index, so that queries matching a good part of
+// the entries don't spend their time on them.
+func (e *entry) labelFor(text string) string {
+	switch e.field {
+	case fieldName:
+		return e.artist + " - Artist/Band"
+	case fieldMember:
+		return text + " - Member of " + e.artist
+	case fieldFirstAlbum:
+		return text + " - First Album of " + e.artist
+	case fieldCreationDate:
+		return text + " - Creation Date of " + e.artist
+	case fieldConcertDate:
+		return text + " - Concert date at " + e.other + " for " + e.artist
+	case fieldConcertLocation:
+		return text + " - Concert location on " + e.other + " for " + e.artist
+	}
+	return ""
+}
+
+// Search runs a full query against the index: see the package-level
+// Search.
+func (ix *Index) Search(query string) []SearchResult {
+	return search(query, ix.SearchAll, ix.Weights)
+}
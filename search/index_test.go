@@ -0,0 +1,136 @@
+package search
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"groupie-tracker/models"
+)
+
+func indexFixture() ([]models.Artists, func(int) (*models.Relations, error)) {
+	artists := []models.Artists{
+		{ID: 1, Name: "Queen", Members: []string{"Freddie Mercury", "Brian May"}, CreationDate: 1970, FirstAlbum: "14-12-1973"},
+		{ID: 2, Name: "Scorpions", Members: []string{"Klaus Meine"}, CreationDate: 1965, FirstAlbum: "01-01-1972"},
+		{ID: 3, Name: "Pink Floyd", Members: []string{"Roger Waters"}, CreationDate: 1965, FirstAlbum: "05-08-1967"},
+	}
+	relations := map[int]*models.Relations{
+		1: {SortedLocations: []string{"osaka-japan"}, DatesLocations: map[string][]string{"osaka-japan": {"28-01-2020"}}},
+		2: {SortedLocations: []string{"queensland-australia"}, DatesLocations: map[string][]string{"queensland-australia": {"24-02-2020"}}},
+	}
+	getRelations := func(id int) (*models.Relations, error) {
+		if rel, ok := relations[id]; ok {
+			return rel, nil
+		}
+		return nil, errors.New("not found")
+	}
+	return artists, getRelations
+}
+
+func TestIndexSearchAll(t *testing.T) {
+	ix := NewIndex(indexFixture())
+	tests := []struct {
+		query string
+		want  []SearchResult
+	}{
+		{"queen", []SearchResult{
+			{Label: "Queen - Artist/Band", ID: 1, Category: "artist", Method: MethodPrefix, Field: "name", Value: "Queen"},
+			{Label: "queensland-australia - Concert location on 24-02-2020 for Scorpions", ID: 2, Category: "concert", Method: MethodPrefix, Field: "location", Value: "queensland-australia"},
+		}},
+		{"may", []SearchResult{
+			{Label: "May Brian - Member of Queen", ID: 1, Category: "member", Method: MethodPrefix, Field: "member", Value: "Brian May"},
+		}},
+		{"ter", []SearchResult{
+			{Label: "Roger Waters - Member of Pink Floyd", ID: 3, Category: "member", Method: MethodContains, Field: "member", Value: "Roger Waters"},
+		}},
+		{"1965", []SearchResult{
+			{Label: "1965 - Creation Date of Scorpions", ID: 2, Category: "creation_date", Method: MethodPrefix, Field: "creation_date", Value: "1965"},
+			{Label: "1965 - Creation Date of Pink Floyd", ID: 3, Category: "creation_date", Method: MethodPrefix, Field: "creation_date", Value: "1965"},
+		}},
+		{"01-2020", []SearchResult{
+			{Label: "28-01-2020 - Concert date at osaka-japan for Queen", ID: 1, Category: "concert", Method: MethodContains, Field: "date", Value: "28-01-2020"},
+		}},
+		{"saka-jap", []SearchResult{
+			{Label: "osaka-japan - Concert location on 28-01-2020 for Queen", ID: 1, Category: "concert", Method: MethodContains, Field: "location", Value: "osaka-japan"},
+		}},
+		{"nothing", []SearchResult{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			got := ix.SearchAll(tt.query)
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d results %v, want %d", len(got), got, len(tt.want))
+			}
+			for i := range tt.want {
+				got[i].Score = 0 // see TestScoring
+				if !reflect.DeepEqual(got[i], tt.want[i]) {
+					t.Errorf("result %d = %+v, want %+v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestCurrentIndex(t *testing.T) {
+	previous := current.Load()
+	defer current.Store(previous)
+
+	current.Store(nil)
+	if got := Current().Search("queen"); len(got) != 0 {
+		t.Errorf("empty index returned %v", got)
+	}
+	Rebuild(indexFixture())
+	if got := Current().Search("queen japan"); len(got) == 0 || got[0].Label != "Queen - Artist/Band" {
+		t.Errorf("Search(queen japan) = %v, want Queen first", got)
+	}
+}
+
+// benchmarkData builds a dataset about the size of the real API's.
+func benchmarkData() ([]models.Artists, func(int) (*models.Relations, error)) {
+	countries := []string{"usa", "uk", "germany", "france", "japan", "australia", "brazil", "canada"}
+	var artists []models.Artists
+	relations := map[int]*models.Relations{}
+	for id := 1; id <= 52; id++ {
+		artists = append(artists, models.Artists{
+			ID:           id,
+			Name:         fmt.Sprintf("Band %d", id),
+			Members:      []string{fmt.Sprintf("Singer%d Smith", id), fmt.Sprintf("Drummer%d Jones", id), fmt.Sprintf("Bassist%d Brown", id)},
+			CreationDate: 1960 + id%50,
+			FirstAlbum:   fmt.Sprintf("%02d-%02d-%d", 1+id%28, 1+id%12, 1965+id%50),
+		})
+		rel := &models.Relations{ID: id, DatesLocations: map[string][]string{}}
+		for i := 0; i < 10; i++ {
+			loc := fmt.Sprintf("city_%d-%s", (id*7+i)%120, countries[(id+i)%len(countries)])
+			rel.SortedLocations = append(rel.SortedLocations, loc)
+			for d := 0; d < 3; d++ {
+				rel.DatesLocations[loc] = append(rel.DatesLocations[loc], fmt.Sprintf("%02d-%02d-20%02d", 1+d*9, 1+i, 10+id%10))
+			}
+		}
+		relations[id] = rel
+	}
+	return artists, func(id int) (*models.Relations, error) { return relations[id], nil }
+}
+
+// BenchmarkIndexSearch times queries on a dataset the size of the real
+// API's. All of them answer in under a millisecond, including one as broad
+// as "ci", which builds some 1,500 results; results/op reports how many.
+func BenchmarkIndexSearch(b *testing.B) {
+	ix := NewIndex(benchmarkData())
+	for _, query := range []string{"band 12", "smith", "smiht", "japan", "2015", "ci"} {
+		b.Run(query, func(b *testing.B) {
+			var results []SearchResult
+			for i := 0; i < b.N; i++ {
+				results = ix.Search(query)
+			}
+			b.ReportMetric(float64(len(results)), "results/op")
+		})
+	}
+}
+
+func BenchmarkNewIndex(b *testing.B) {
+	artists, getRelations := benchmarkData()
+	for i := 0; i < b.N; i++ {
+		NewIndex(artists, getRelations)
+	}
+}
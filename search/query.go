@@ -0,0 +1,269 @@
+package search
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// The query language: whitespace separated clauses, all of which a result's
+// artist has to match.
+//
+//	queen                 free text, as typed in the search box
+//	"freddie mercury"     a phrase: the words in order, in the same field
+//	member:freddie        a field: artist (or name), member, location, country,
+//	                      date, album, year, members or category; other
+//	                      words before a colon, as in ac:dc, are free text
+//	year:1970..1980       a range; also <1990, <=1990, >1990, >=1990, 1990..
+//	                      and ..1990, for year, album and members
+//	-beatles              excludes the artists matching the clause
+//
+// Field values may be phrases too: member:"brian may".
+
+// SyntaxError is an invalid query. Column counts characters from 1.
+type SyntaxError struct {
+	Column int
+	Msg    string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("invalid search at column %d: %s", e.Column, e.Msg)
+}
+
+// Query is a parsed search query.
+type Query struct {
+	Clauses []Clause
+}
+
+// Clause is one condition of a query.
+type Clause struct {
+	Negate bool
+	Field  string // one of the Fields names, "" for free text
+	Text   string // the word or phrase; for category, the category
+	Phrase bool
+	Range  *Range // set for numeric values of year, album and members
+	Column int
+}
+
+// Range is an inclusive range of numbers.
+type Range struct {
+	Min, Max int
+}
+
+// Contains reports whether n is in r.
+func (r Range) Contains(n int) bool {
+	return n >= r.Min && n <= r.Max
+}
+
+// Fields are the field names a clause can start with.
+var Fields = []string{"artist", "name", "member", "location", "country", "date", "album", "year", "members", "category"}
+
+// Categories are the categories results come in, for category:.
+var Categories = []string{"artist", "member", "first_album", "creation_date", "concert"}
+
+type tokenKind int
+
+const (
+	tokWord tokenKind = iota
+	tokPhrase
+	tokField // the text is the field name; its value is the next token
+	tokMinus
+)
+
+type token struct {
+	kind   tokenKind
+	text   string
+	column int
+}
+
+// lex splits a query into tokens.
+func lex(query string) ([]token, error) {
+	runes := []rune(query)
+	var tokens []token
+	i := 0
+	for i < len(runes) {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '-' && startsClause(runes, i):
+			if i+1 == len(runes) || unicode.IsSpace(runes[i+1]) {
+				return nil, &SyntaxError{i + 1, `"-" must be followed by what to exclude, as in -beatles`}
+			}
+			tokens = append(tokens, token{tokMinus, "-", i + 1})
+			i++
+		case r == '"':
+			end := i + 1
+			for end < len(runes) && runes[end] != '"' {
+				end++
+			}
+			if end == len(runes) {
+				return nil, &SyntaxError{i + 1, "missing closing quote"}
+			}
+			text := strings.TrimSpace(string(runes[i+1 : end]))
+			if text == "" {
+				return nil, &SyntaxError{i + 1, "empty quotes"}
+			}
+			tokens = append(tokens, token{tokPhrase, text, i + 1})
+			i = end + 1
+		default:
+			start := i
+			for i < len(runes) && !unicode.IsSpace(runes[i]) && runes[i] != '"' && runes[i] != ':' {
+				i++
+			}
+			if i < len(runes) && runes[i] == ':' {
+				if i == start {
+					return nil, &SyntaxError{start + 1, `missing field name before ":"`}
+				}
+				field := string(runes[start:i])
+				if !knownField(strings.ToLower(field)) {
+					// Not a field, as in "ac:dc": a word with a colon
+					for i < len(runes) && !unicode.IsSpace(runes[i]) && runes[i] != '"' {
+						i++
+					}
+					tokens = append(tokens, token{tokWord, string(runes[start:i]), start + 1})
+					continue
+				}
+				tokens = append(tokens, token{tokField, field, start + 1})
+				i++
+				if i == len(runes) || unicode.IsSpace(runes[i]) {
+					return nil, &SyntaxError{start + 1, fmt.Sprintf("missing value after %s:", field)}
+				}
+				if runes[i] != '"' {
+					valueStart := i
+					for i < len(runes) && !unicode.IsSpace(runes[i]) && runes[i] != '"' {
+						i++
+					}
+					tokens = append(tokens, token{tokWord, string(runes[valueStart:i]), valueStart + 1})
+				}
+				continue
+			}
+			tokens = append(tokens, token{tokWord, string(runes[start:i]), start + 1})
+		}
+	}
+	return tokens, nil
+}
+
+// startsClause reports whether runes[i] is at the start of a clause, so a
+// '-' there negates it rather than being part of a word like "new-york".
+func startsClause(runes []rune, i int) bool {
+	return i == 0 || unicode.IsSpace(runes[i-1])
+}
+
+// Parse parses a query. Errors are *SyntaxError.
+func Parse(query string) (Query, error) {
+	tokens, err := lex(query)
+	if err != nil {
+		return Query{}, err
+	}
+	var q Query
+	for i := 0; i < len(tokens); i++ {
+		c := Clause{Column: tokens[i].column}
+		if tokens[i].kind == tokMinus {
+			c.Negate = true
+			i++
+		}
+		tok := tokens[i]
+		if tok.kind == tokField {
+			field := strings.ToLower(tok.text) // lex only makes tokens of known fields
+			if field == "name" {
+				field = "artist"
+			}
+			i++
+			if i == len(tokens) || tokens[i].kind != tokWord && tokens[i].kind != tokPhrase {
+				return Query{}, &SyntaxError{tok.column, fmt.Sprintf("missing value after %s:", tok.text)}
+			}
+			c.Field = field
+			tok = tokens[i]
+			if err := c.setValue(tok); err != nil {
+				return Query{}, err
+			}
+		} else {
+			c.Text, c.Phrase = tok.text, tok.kind == tokPhrase
+		}
+		q.Clauses = append(q.Clauses, c)
+	}
+	return q, nil
+}
+
+// setValue sets the value of a field clause from tok, checking it suits
+// the field.
+func (c *Clause) setValue(tok token) error {
+	c.Text, c.Phrase = tok.text, tok.kind == tokPhrase
+	switch c.Field {
+	case "year", "members":
+		r, err := parseRange(tok.text)
+		if err != nil || c.Phrase {
+			example := "1970..1980 or <1990"
+			if c.Field == "members" {
+				example = "4 or 3..5"
+			}
+			return &SyntaxError{tok.column, fmt.Sprintf("%s: expected a number or a range like %s, got %q", c.Field, example, tok.text)}
+		}
+		c.Range = &r
+	case "album":
+		// A year or a range of years, or else text from the date.
+		if r, err := parseRange(tok.text); err == nil && !c.Phrase {
+			c.Range = &r
+		}
+	case "category":
+		category := strings.ToLower(tok.text)
+		for _, known := range Categories {
+			if category == known {
+				c.Text = category
+				return nil
+			}
+		}
+		return &SyntaxError{tok.column, fmt.Sprintf("unknown category %q; use one of %s", tok.text, strings.Join(Categories, ", "))}
+	}
+	return nil
+}
+
+func knownField(field string) bool {
+	for _, f := range Fields {
+		if field == f {
+			return true
+		}
+	}
+	return false
+}
+
+// parseRange parses "N", "N..M", "N..", "..M", "<N", "<=N", ">N" or ">=N".
+func parseRange(s string) (Range, error) {
+	r := Range{Min: math.MinInt, Max: math.MaxInt}
+	var err error
+	switch {
+	case strings.HasPrefix(s, "<="):
+		r.Max, err = strconv.Atoi(s[2:])
+	case strings.HasPrefix(s, "<"):
+		r.Max, err = strconv.Atoi(s[1:])
+		r.Max--
+	case strings.HasPrefix(s, ">="):
+		r.Min, err = strconv.Atoi(s[2:])
+	case strings.HasPrefix(s, ">"):
+		r.Min, err = strconv.Atoi(s[1:])
+		r.Min++
+	case strings.Contains(s, ".."):
+		low, high, _ := strings.Cut(s, "..")
+		if low == "" && high == "" {
+			return r, fmt.Errorf("empty range")
+		}
+		if low != "" {
+			if r.Min, err = strconv.Atoi(low); err != nil {
+				return r, err
+			}
+		}
+		if high != "" {
+			r.Max, err = strconv.Atoi(high)
+		}
+	default:
+		r.Min, err = strconv.Atoi(s)
+		r.Max = r.Min
+	}
+	if err == nil && r.Min > r.Max {
+		err = fmt.Errorf("empty range")
+	}
+	return r, err
+}
@@ -0,0 +1,174 @@
+package search
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+
+	"groupie-tracker/models"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		query string
+		want  []Clause
+	}{
+		{"pink floyd", []Clause{{Text: "pink", Column: 1}, {Text: "floyd", Column: 6}}},
+		{`"freddie mercury" queen`, []Clause{{Text: "freddie mercury", Phrase: true, Column: 1}, {Text: "queen", Column: 19}}},
+		{"member:freddie", []Clause{{Field: "member", Text: "freddie", Column: 1}}},
+		{`Member:"brian may"`, []Clause{{Field: "member", Text: "brian may", Phrase: true, Column: 1}}},
+		{"name:queen", []Clause{{Field: "artist", Text: "queen", Column: 1}}},
+		{"-beatles new-york", []Clause{{Negate: true, Text: "beatles", Column: 1}, {Text: "new-york", Column: 10}}},
+		{"-country:uk", []Clause{{Negate: true, Field: "country", Text: "uk", Column: 1}}},
+		{"year:1970..1980", []Clause{{Field: "year", Text: "1970..1980", Range: &Range{1970, 1980}, Column: 1}}},
+		{"album:<1990", []Clause{{Field: "album", Text: "<1990", Range: &Range{math.MinInt, 1989}, Column: 1}}},
+		{"album:14-12-1973", []Clause{{Field: "album", Text: "14-12-1973", Column: 1}}},
+		{"members:>=4", []Clause{{Field: "members", Text: ">=4", Range: &Range{4, math.MaxInt}, Column: 1}}},
+		{"category:Member", []Clause{{Field: "category", Text: "member", Column: 1}}},
+		{"colour:red", []Clause{{Text: "colour:red", Column: 1}}},
+		{`ac:dc re: "live aid"`, []Clause{{Text: "ac:dc", Column: 1}, {Text: "re:", Column: 7}, {Text: "live aid", Phrase: true, Column: 11}}},
+		{"-feat:queen", []Clause{{Negate: true, Text: "feat:queen", Column: 1}}},
+		{"  ", nil},
+	}
+	for _, tt := range tests {
+		q, err := Parse(tt.query)
+		if err != nil {
+			t.Errorf("Parse(%q) returned %v", tt.query, err)
+			continue
+		}
+		if !reflect.DeepEqual(q.Clauses, tt.want) {
+			t.Errorf("Parse(%q) = %+v, want %+v", tt.query, q.Clauses, tt.want)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		query  string
+		column int
+		msg    string
+	}{
+		{`queen "freddie`, 7, "missing closing quote"},
+		{`""`, 1, "empty quotes"},
+		{"queen -", 7, `"-" must be followed`},
+		{"member: freddie", 1, "missing value after member:"},
+		{":queen", 1, "missing field name"},
+		{"year:nineties", 6, "year: expected a number or a range"},
+		{"year:1990..1980", 6, "year: expected"},
+		{"members:many", 9, "members: expected a number or a range like 4 or 3..5"},
+		{"category:band", 10, `unknown category "band"`},
+	}
+	for _, tt := range tests {
+		_, err := Parse(tt.query)
+		var syntaxErr *SyntaxError
+		if !errors.As(err, &syntaxErr) {
+			t.Errorf("Parse(%q) error = %v, want a *SyntaxError", tt.query, err)
+			continue
+		}
+		if syntaxErr.Column != tt.column || !strings.Contains(syntaxErr.Msg, tt.msg) {
+			t.Errorf("Parse(%q) error = %q at column %d, want %q at column %d", tt.query, syntaxErr.Msg, syntaxErr.Column, tt.msg, tt.column)
+		}
+	}
+}
+
+func queryFixture() *Index {
+	artists := []models.Artists{
+		{ID: 1, Name: "Queen", Members: []string{"Freddie Mercury", "Brian May", "Roger Taylor", "John Deacon"}, CreationDate: 1970, FirstAlbum: "14-12-1973"},
+		{ID: 2, Name: "The Beatles", Members: []string{"John Lennon", "Paul McCartney", "George Harrison", "Ringo Starr"}, CreationDate: 1960, FirstAlbum: "22-03-1963"},
+		{ID: 3, Name: "Arctic Monkeys", Members: []string{"Alex Turner", "Matt Helders", "Jamie Cook"}, CreationDate: 2002, FirstAlbum: "23-01-2006"},
+	}
+	relations := map[int]*models.Relations{
+		1: {SortedLocations: []string{"london-uk", "osaka-japan"}, DatesLocations: map[string][]string{"london-uk": {"12-07-1986"}, "osaka-japan": {"28-01-2020"}}},
+		2: {SortedLocations: []string{"liverpool-uk", "new_york-usa"}, DatesLocations: map[string][]string{"liverpool-uk": {"27-12-1961"}, "new_york-usa": {"15-08-1965"}}},
+		3: {SortedLocations: []string{"new_york-usa"}, DatesLocations: map[string][]string{"new_york-usa": {"01-06-2014"}}},
+	}
+	return NewIndex(artists, func(id int) (*models.Relations, error) { return relations[id], nil })
+}
+
+func TestQuery(t *testing.T) {
+	ix := queryFixture()
+	tests := []struct {
+		query string
+		want  []string // labels, in order
+	}{
+		{"member:john", []string{"John Deacon - Member of Queen", "John Lennon - Member of The Beatles"}},
+		{`"brian may"`, []string{"Brian May - Member of Queen"}},
+		{`"may brian"`, nil},
+		{"country:uk", []string{
+			"london-uk - Concert location on 12-07-1986 for Queen",
+			"liverpool-uk - Concert location on 27-12-1961 for The Beatles",
+		}},
+		{"country:united_kingdom", []string{
+			"london-uk - Concert location on 12-07-1986 for Queen",
+			"liverpool-uk - Concert location on 27-12-1961 for The Beatles",
+		}},
+		{"country:us", []string{
+			"new_york-usa - Concert location on 15-08-1965 for The Beatles",
+			"new_york-usa - Concert location on 01-06-2014 for Arctic Monkeys",
+		}},
+		{"country:jap", nil},
+		{"country:uk -beatles", []string{"london-uk - Concert location on 12-07-1986 for Queen"}},
+		{"year:1965..1980", []string{"1970 - Creation Date of Queen"}},
+		{"album:<1970", []string{"22-03-1963 - First Album of The Beatles"}},
+		{"members:3", []string{"Arctic Monkeys - Artist/Band"}},
+		{`location:"new york" year:>2000`, []string{
+			"new_york-usa - Concert location on 01-06-2014 for Arctic Monkeys",
+			"2002 - Creation Date of Arctic Monkeys",
+		}},
+		{"john category:member -member:lennon", []string{"John Deacon - Member of Queen"}},
+		{"john -category:member", nil},
+		{"-queen", nil},
+	}
+	for _, tt := range tests {
+		got, err := ix.Query(tt.query)
+		if err != nil {
+			t.Errorf("Query(%q) returned %v", tt.query, err)
+			continue
+		}
+		if !reflect.DeepEqual(labels(got), tt.want) {
+			t.Errorf("Query(%q) = %q, want %q", tt.query, labels(got), tt.want)
+		}
+	}
+}
+
+func TestQueryCountryMatchesWholeCountry(t *testing.T) {
+	artists := []models.Artists{{ID: 1, Name: "Okean Elzy", Members: []string{"Svyatoslav Vakarchuk"}, CreationDate: 1994, FirstAlbum: "01-01-1998"}}
+	relations := &models.Relations{
+		SortedLocations: []string{"kyiv-ukraine", "london-uk", "usti_nad_labem-czechia"},
+		DatesLocations:  map[string][]string{"kyiv-ukraine": {"01-05-2019"}, "london-uk": {"02-06-2019"}, "usti_nad_labem-czechia": {"03-07-2019"}},
+	}
+	ix := NewIndex(artists, func(int) (*models.Relations, error) { return relations, nil })
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"country:uk", []string{"london-uk - Concert location on 02-06-2019 for Okean Elzy"}},
+		{"country:ukraine", []string{"kyiv-ukraine - Concert location on 01-05-2019 for Okean Elzy"}},
+		{"country:us", nil},
+		{"country:czech", nil},
+	}
+	for _, tt := range tests {
+		got, err := ix.Query(tt.query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(labels(got), tt.want) {
+			t.Errorf("Query(%q) = %q, want %q", tt.query, labels(got), tt.want)
+		}
+	}
+}
+
+func TestQueryFreeTextMatchesSearch(t *testing.T) {
+	ix := queryFixture()
+	for _, query := range []string{"queen", "john", "new york", "uk", "1970", "beatels"} {
+		got, err := ix.Query(query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := ix.Search(query); !reflect.DeepEqual(got, want) {
+			t.Errorf("Query(%q) = %q, want %q as from Search", query, labels(got), labels(want))
+		}
+	}
+}
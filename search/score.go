@@ -0,0 +1,159 @@
+package search
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Weights tunes how results are scored. A result scores the weight of its
+// field times the weight of how well it matched, plus Coverage times the
+// share of the query's words it matched and Position when it matched at
+// the start of the field, less the further into it.
+type Weights struct {
+	Name, Member, Location, Date, FirstAlbum, CreationDate float64
+	Exact, Prefix, Contains, Fuzzy                         float64
+	Coverage, Position                                     float64
+}
+
+// DefaultWeights put artist names before members, members before
+// locations and locations before dates.
+var DefaultWeights = Weights{
+	Name: 10, Member: 8, Location: 6, Date: 4, FirstAlbum: 3, CreationDate: 3,
+	Exact: 1, Prefix: 0.8, Contains: 0.5, Fuzzy: 0.3,
+	Coverage: 5, Position: 1,
+}
+
+// weights are the weights new indexes start with: DefaultWeights, with
+// the overrides from $SEARCH_WEIGHTS.
+var weights = envWeights("SEARCH_WEIGHTS")
+
+func envWeights(name string) Weights {
+	w, err := ParseWeights(os.Getenv(name), DefaultWeights)
+	if err != nil {
+		fmt.Printf("Ignoring %s: %v\n", name, err)
+		return DefaultWeights
+	}
+	return w
+}
+
+// ParseWeights overrides weights in w from a list such as
+// "name=12,location=4.5". Keys are the lowercase field names, with
+// first_album and creation_date for the two-word ones.
+func ParseWeights(s string, w Weights) (Weights, error) {
+	fields := map[string]*float64{
+		"name": &w.Name, "member": &w.Member, "location": &w.Location, "date": &w.Date,
+		"first_album": &w.FirstAlbum, "creation_date": &w.CreationDate,
+		"exact": &w.Exact, "prefix": &w.Prefix, "contains": &w.Contains, "fuzzy": &w.Fuzzy,
+		"coverage": &w.Coverage, "position": &w.Position,
+	}
+	for _, pair := range strings.Split(s, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		key, value, _ := strings.Cut(pair, "=")
+		key = strings.ToLower(strings.TrimSpace(key))
+		target, ok := fields[key]
+		if !ok {
+			return w, fmt.Errorf("unknown weight %q", key)
+		}
+		v, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil || v < 0 {
+			return w, fmt.Errorf("weight %s must be a non-negative number", key)
+		}
+		*target = v
+	}
+	return w, nil
+}
+
+// quality is how well a term matched a query, best last.
+type quality int8
+
+const (
+	qualityFuzzy quality = iota + 1
+	qualityContains
+	qualityPrefix
+	qualityExact
+)
+
+func (q quality) method() SearchMethod {
+	switch q {
+	case qualityFuzzy:
+		return MethodFuzzy
+	case qualityContains:
+		return MethodContains
+	}
+	return MethodPrefix
+}
+
+func (w Weights) field(f field) float64 {
+	switch f {
+	case fieldName:
+		return w.Name
+	case fieldMember:
+		return w.Member
+	case fieldFirstAlbum:
+		return w.FirstAlbum
+	case fieldCreationDate:
+		return w.CreationDate
+	case fieldConcertDate:
+		return w.Date
+	}
+	return w.Location
+}
+
+func (w Weights) match(q quality) float64 {
+	switch q {
+	case qualityExact:
+		return w.Exact
+	case qualityPrefix:
+		return w.Prefix
+	case qualityContains:
+		return w.Contains
+	}
+	return w.Fuzzy
+}
+
+// score scores a match in entry e, before coverage.
+func (w Weights) score(e *entry, m termMatch) float64 {
+	position := float64(e.word)
+	if m.quality == qualityContains && m.length > 0 {
+		position += float64(m.offset) / float64(m.length)
+	}
+	return w.field(e.field)*w.match(m.quality) + w.Position/(1+position)
+}
+
+// addCoverage adds to each result its share of the query's tokens, which
+// are matched by results with the same standardized label.
+func (w Weights) addCoverage(results []SearchResult, resultsPerToken [][]SearchResult) {
+	if len(resultsPerToken) == 1 {
+		// every result matches the only token
+		for i := range results {
+			results[i].Score += w.Coverage
+		}
+		return
+	}
+	ids := make(map[int]bool, len(results))
+	for _, r := range results {
+		ids[r.ID] = true
+	}
+	tokens := make(map[string]int)
+	for _, tokenResults := range resultsPerToken {
+		seen := make(map[string]bool)
+		for _, r := range tokenResults {
+			if !ids[r.ID] {
+				continue // labels are by artist, so it can't be one of results
+			}
+			key := standardLabel(r.Label)
+			if !seen[key] {
+				seen[key] = true
+				tokens[key]++
+			}
+		}
+	}
+	for i := range results {
+		share := float64(tokens[standardLabel(results[i].Label)]) / float64(len(resultsPerToken))
+		results[i].Score += w.Coverage * share
+	}
+}
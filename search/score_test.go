@@ -0,0 +1,116 @@
+package search
+
+import (
+	"testing"
+
+	"groupie-tracker/models"
+)
+
+func scoringFixture() *Index {
+	artists := []models.Artists{
+		// Scorpions come first, so without scoring their Queensland concert
+		// would be listed before Queen.
+		{ID: 1, Name: "Scorpions", Members: []string{"Klaus Meine"}, CreationDate: 1965, FirstAlbum: "01-01-1972"},
+		{ID: 2, Name: "Queen", Members: []string{"Freddie Mercury", "Roger Taylor"}, CreationDate: 1970, FirstAlbum: "14-12-1973"},
+		{ID: 3, Name: "Queens of the Stone Age", Members: []string{"Josh Homme"}, CreationDate: 1996, FirstAlbum: "06-10-1998"},
+		{ID: 4, Name: "Mercury Rev", Members: []string{"Jonathan Donahue"}, CreationDate: 1989, FirstAlbum: "01-01-1991"},
+	}
+	relations := map[int]*models.Relations{
+		1: {SortedLocations: []string{"queensland-australia"}, DatesLocations: map[string][]string{"queensland-australia": {"24-02-2020"}}},
+		2: {SortedLocations: []string{"osaka-japan"}, DatesLocations: map[string][]string{"osaka-japan": {"28-01-2020"}}},
+	}
+	return NewIndex(artists, func(id int) (*models.Relations, error) {
+		if rel, ok := relations[id]; ok {
+			return rel, nil
+		}
+		return &models.Relations{}, nil
+	})
+}
+
+func labels(results []SearchResult) []string {
+	var out []string
+	for _, r := range results {
+		out = append(out, r.Label)
+	}
+	return out
+}
+
+func TestScoring(t *testing.T) {
+	ix := scoringFixture()
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		// Exact name, then a longer name, then a location.
+		{"queen", []string{
+			"Queen - Artist/Band",
+			"Queens of the Stone Age - Artist/Band",
+			"queensland-australia - Concert location on 24-02-2020 for Scorpions",
+		}},
+		// A member whose whole name matches before the band named after one word.
+		{"freddie mercury", []string{
+			"Freddie Mercury - Member of Queen",
+		}},
+		// An artist name before a member with the same word.
+		{"mercury", []string{
+			"Mercury Rev - Artist/Band",
+			"Mercury Freddie - Member of Queen",
+		}},
+	}
+	for _, tt := range tests {
+		got := labels(ix.Search(tt.query))
+		if len(got) < len(tt.want) {
+			t.Fatalf("Search(%q) = %q, want %q first", tt.query, got, tt.want)
+		}
+		for i := range tt.want {
+			if got[i] != tt.want[i] {
+				t.Errorf("Search(%q) = %q, want %q first", tt.query, got, tt.want)
+				break
+			}
+		}
+	}
+}
+
+func TestScoringWeights(t *testing.T) {
+	ix := scoringFixture()
+	w, err := ParseWeights("location=30", DefaultWeights)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ix.Weights = w
+	got := ix.Search("queen")
+	if len(got) == 0 || got[0].Field != "location" {
+		t.Errorf("Search(queen) = %q, want the location first once it weighs most", labels(got))
+	}
+}
+
+func TestSortResultsStable(t *testing.T) {
+	results := []SearchResult{
+		{Label: "b", Score: 1, Method: MethodContains},
+		{Label: "a", Score: 2, Method: MethodContains},
+		{Label: "c", Score: 1, Method: MethodPrefix},
+		{Label: "d", Score: 1, Method: MethodContains},
+	}
+	SortResults(results)
+	want := []string{"a", "c", "b", "d"}
+	for i, r := range results {
+		if r.Label != want[i] {
+			t.Fatalf("SortResults order = %q, want %q", labels(results), want)
+		}
+	}
+}
+
+func TestParseWeights(t *testing.T) {
+	w, err := ParseWeights(" name=12, first_album=0.5 ,", DefaultWeights)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w.Name != 12 || w.FirstAlbum != 0.5 || w.Member != DefaultWeights.Member {
+		t.Errorf("ParseWeights = %+v", w)
+	}
+	for _, bad := range []string{"colour=1", "name=heavy", "name=-1"} {
+		if _, err := ParseWeights(bad, DefaultWeights); err == nil {
+			t.Errorf("ParseWeights(%q) returned no error", bad)
+		}
+	}
+}
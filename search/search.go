@@ -2,8 +2,8 @@ package search
 
 import (
 	"sort"
-	"strconv"
 	"strings"
+	"unicode/utf8"
 
 	"groupie-tracker/models"
 )
@@ -11,6 +11,7 @@ import (
 type SearchMethod int
 
 const (
+	MethodFuzzy    SearchMethod = -1 // within a few typos of a word
 	MethodContains SearchMethod = 0
 	MethodPrefix   SearchMethod = 1
 )
@@ -20,144 +21,22 @@ type SearchResult struct {
 	ID    int
 	Category string
 	Method	 SearchMethod
+	Field    string  // the part of the artist that matched: name, member, first_album, creation_date, date or location
+	Value    string  // the text of that field, as in the data
+	Highlights []Span // the parts of Value the query matched; see Search and Index.Query
+	Score    float64 // relevance, higher first; see Weights
 }
 
 // SearchAll searches artists by name, members, first album, creation date, locations, and dates based on the query string.
 // It expects a single-word query. Thus, queries like "Freddie Mercury" should be split into []string{"Freddie" "Mercury"}
+// It indexes the artists first; to run many queries, build an Index once instead.
 func SearchAll(query string, artists []models.Artists, getRelations func(int) (*models.Relations, error)) []SearchResult {
-	results := []SearchResult{}
-	searchQuery := strings.ToLower(query)
-	for _, artist := range artists {
-		// Search by name
-		for _, part := range strings.Fields(strings.ToLower(artist.Name)) {
-			if strings.HasPrefix(part, searchQuery) {
-				results = append(results, SearchResult{
-					Label:    artist.Name + " - Artist/Band",
-					ID:       artist.ID,
-					Category: "artist",
-					Method:   MethodPrefix,
-				})
-			} else if strings.Contains(part, searchQuery) {
-				results = append(results, SearchResult{
-					Label:    artist.Name + " - Artist/Band",
-					ID:       artist.ID,
-					Category: "artist",
-					Method:   MethodContains,
-				})
-			}
-		}
-		// Search by members
-		for _, member := range artist.Members {
-			for _, part := range strings.Fields(strings.ToLower(member)) {
-				if strings.HasPrefix(part, searchQuery) {
-					fullName := member
-					// If match is on surname (not first word), reorder to surname first
-					parts := strings.Fields(member)
-					name := parts[0]
-					surname := parts[len(parts)-1]
-					if len(parts) > 1 && strings.HasPrefix(strings.ToLower(surname), searchQuery) {
-						fullName = surname + " " + name
-					}
-					results = append(results, SearchResult{
-						Label:    fullName + " - Member of " + artist.Name,
-						ID:       artist.ID,
-						Category: "member",
-						Method:   MethodPrefix,
-					})
-				} else if strings.Contains(part, searchQuery) {
-					results = append(results, SearchResult{
-						Label:    member + " - Member of " + artist.Name,
-						ID:       artist.ID,
-						Category: "member",
-						Method:   MethodContains,
-					})
-				}
-			}
-		}
-		// Search by first album
-		if strings.HasPrefix(artist.FirstAlbum, searchQuery) {
-			results = append(results, SearchResult{
-				Label:    artist.FirstAlbum + " - First Album of " + artist.Name,
-				ID:       artist.ID,
-				Category: "first_album",
-				Method:   MethodPrefix,
-			})
-		} else if strings.Contains(artist.FirstAlbum, searchQuery) {
-			results = append(results, SearchResult{
-				Label:    artist.FirstAlbum + " - First Album of " + artist.Name,
-				ID:       artist.ID,
-				Category: "first_album",
-				Method:   MethodContains,
-			})
-		}
-		// Search by creation date
-		creationDateStr := strconv.Itoa(artist.CreationDate)
-		if strings.HasPrefix(creationDateStr, searchQuery) {
-			results = append(results, SearchResult{
-				Label:    creationDateStr + " - Creation Date of " + artist.Name,
-				ID:       artist.ID,
-				Category: "creation_date",
-				Method:   MethodPrefix,
-			})
-		} else if strings.Contains(creationDateStr, searchQuery) {
-			results = append(results, SearchResult{
-				Label:    creationDateStr + " - Creation Date of " + artist.Name,
-				ID:       artist.ID,
-				Category: "creation_date",
-				Method:   MethodContains,
-			})
-		}
-		// Search in Relations
-		rel, err := getRelations(artist.ID)
-		if err != nil {
-			continue
-		}
-		for _, loc := range rel.SortedLocations {
-			dates := rel.DatesLocations[loc]
-			// Search by dates
-			for _, date := range dates {
-				if strings.HasPrefix(date, searchQuery) {
-					results = append(results, SearchResult{
-						Label:    date + " - Concert date at " + loc + " for " + artist.Name,
-						ID:       artist.ID,
-						Category: "concert",
-						Method:   MethodPrefix,
-					})
-				} else if strings.Contains(date, searchQuery) {
-					results = append(results, SearchResult{
-						Label:    date + " - Concert date at " + loc + " for " + artist.Name,
-						ID:       artist.ID,
-						Category: "concert",
-						Method:   MethodContains,
-					})
-				}
-				// Search by location
-				for _, part := range strings.Fields(strings.ToLower(normalize(loc))) {
-					if strings.HasPrefix(part, normalize(searchQuery)) {
-						results = append(results, SearchResult{
-							Label:    loc + " - Concert location on " + date + " for " + artist.Name,
-							ID:       artist.ID,
-							Category: "concert",
-							Method:   MethodPrefix,
-						})
-					} else if strings.Contains(part, normalize(searchQuery)) {
-						results = append(results, SearchResult{
-							Label:    loc + " - Concert location on " + date + " for " + artist.Name,
-							ID:       artist.ID,
-							Category: "concert",
-							Method:   MethodContains,
-						})
-					}
-				}
-			}
-		}
-	}
-	return results
+	return NewIndex(artists, getRelations).SearchAll(query)
 }
 
-// removes punctuation and spaces from string
+// folds the string (see fold) and removes punctuation and spaces from it
 func normalize(s string) string {
-	s = strings.ToLower(s)
+	s = fold(s)
 	s = strings.ReplaceAll(s, "-", "")
 	s = strings.ReplaceAll(s, ",", "")
 	s = strings.ReplaceAll(s, " ", "")
@@ -167,6 +46,8 @@ func normalize(s string) string {
 	return s
 }
 
+// FilterSearch keeps the results of one category, or all of them for "all".
+// Queries can do the same with a category: clause.
 func FilterSearch(results []SearchResult, option string) []SearchResult {
 	if option == "all" {
 		return results
@@ -180,17 +61,42 @@ func FilterSearch(results []SearchResult, option string) []SearchResult {
 	return filtered
 }
 
-// ParseQuery splits the search query into tokens
-// ex. "Pink Floyd" -> {"pink" "floyd"}
+// ParseQuery splits the search query into folded tokens
+// ex. "Pink Floyd" -> {"pink" "floyd"}, "Mötley Crüe" -> {"motley" "crue"}
 func ParseQuery(query string) []string {
-	return strings.Fields(strings.ToLower(query))
+	return strings.Fields(fold(query))
 }
 
-// SortResults sorts the search results by method (prefix matches before contains matches)
+// SortResults sorts the search results by score, then by method (prefix matches before contains matches, then fuzzy matches)
+// Results that tie keep their order.
 func SortResults(results []SearchResult) {
-	sort.Slice(results, func(i, j int) bool {
-		return results[i].Method > results[j].Method
+	// Sorting positions moves less than sorting the results themselves.
+	order := make([]int, len(results))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(i, j int) bool {
+		a, b := &results[order[i]], &results[order[j]]
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		if a.Method != b.Method {
+			return a.Method > b.Method
+		}
+		return order[i] < order[j]
 	})
+	// Move each result to its place, one cycle of the order at a time.
+	for i := range order {
+		if order[i] < 0 {
+			continue
+		}
+		first, j := results[i], i
+		for order[j] != i {
+			results[j] = results[order[j]]
+			order[j], j = -1, order[j]
+		}
+		results[j], order[j] = first, -1
+	}
 }
 
 // MatchResults returns only the results whose IDs appear in all token results
@@ -219,7 +125,15 @@ func MatchResults(tokenResults [][]SearchResult) []SearchResult {
 		}
 	}
 	// Collect ALL results belonging to valid IDs
-	matched := []SearchResult{}
+	count := 0
+	for _, results := range tokenResults {
+		for _, r := range results {
+			if validIDs[r.ID] {
+				count++
+			}
+		}
+	}
+	matched := make([]SearchResult, 0, count)
 	for _, results := range tokenResults {
 		for _, r := range results {
 			if validIDs[r.ID] {
@@ -234,47 +148,89 @@ func MatchResults(tokenResults [][]SearchResult) []SearchResult {
 // It splits the label into two parts with " - " as the delimiter,
 // standardizes the first part by reordering words alphabetically,
 // and then uses the standardized label to identify duplicates.
+// The unique results are moved to the front of results, whose storage the
+// returned slice shares.
 func RemoveDuplicates(results []SearchResult) []SearchResult {
-	seen := make(map[string]bool)
-	unique := []SearchResult{}
-	for _, r := range results {
-		var label string
-		parts := strings.SplitN(r.Label, " - ", 2)
-		firstPart := strings.ToLower(strings.TrimSpace(parts[0]))
-		words := strings.Fields(firstPart)
-		sort.Strings(words)
-		standardName := strings.Join(words, " ")
-		label = standardName + parts[1]
-		// Add to unique results if not seen before
-		if !seen[label] {
-			seen[label] = true
-			unique = append(unique, r)
+	seen := make(map[string]bool, len(results))
+	unique := results[:0]
+	for i := range results {
+		// Add to unique results if not seen before, which adds a label
+		n := len(seen)
+		seen[standardLabel(results[i].Label)] = true
+		if len(seen) > n {
+			if len(unique) < i {
+				results[len(unique)] = results[i]
+			}
+			unique = unique[:len(unique)+1]
 		}
 	}
 	return unique
 }
 
+// standardLabel is label with the words before " - " lowercased and sorted,
+// so "Freddie Mercury - Member of Queen" and "Mercury Freddie - Member of Queen" compare equal.
+func standardLabel(label string) string {
+	name, rest, _ := strings.Cut(label, " - ")
+	if isStandardName(name) {
+		return label // most labels are, and are checked without allocating
+	}
+	standardName := strings.ToLower(strings.TrimSpace(name))
+	if strings.Contains(standardName, " ") {
+		words := strings.Fields(standardName)
+		sort.Strings(words)
+		standardName = strings.Join(words, " ")
+	}
+	return standardName + " - " + rest
+}
+
+// isStandardName reports whether name is a single ASCII word without upper
+// case letters, which standardLabel leaves as it is.
+func isStandardName(name string) bool {
+	for i := 0; i < len(name); i++ {
+		if c := name[i]; c <= ' ' || 'A' <= c && c <= 'Z' || c >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
+
 // Search performs a full search based on the query string.
 // It splits the query into tokens, searches for each token, matches results that appear in all tokens,
 // sorts the results, and removes duplicates.
 func Search(query string, artists []models.Artists, getRelations func(int) (*models.Relations, error)) []SearchResult {
+	return NewIndex(artists, getRelations).Search(query)
+}
+
+// search runs a query, looking up each token with searchAll and scoring
+// coverage with w.
+func search(query string, searchAll func(string) []SearchResult, w Weights) []SearchResult {
 	// Tokenize the query
 	tokens := ParseQuery(query)
+	terms := make([]highlightTerm, len(tokens))
+	for i, token := range tokens {
+		terms[i] = highlightTerm{text: token}
+	}
 	if len(tokens) == 1 {
 		// Single token search
-		results := SearchAll(tokens[0], artists, getRelations)
+		results := searchAll(tokens[0])
+		w.addCoverage(results, [][]SearchResult{results})
 		SortResults(results)
-		return RemoveDuplicates(results)
+		results = RemoveDuplicates(results)
+		addHighlights(results, terms)
+		return results
 	}
 	// Multi-token search
 	resultsPerToken := [][]SearchResult{}
 	for _, token := range tokens {
-		tokenResults := SearchAll(token, artists, getRelations)
+		tokenResults := searchAll(token)
 		resultsPerToken = append(resultsPerToken, tokenResults)
 	}
 	// Match results that appear in all tokens
 	results := MatchResults(resultsPerToken)
+	w.addCoverage(results, resultsPerToken)
 	// Sort results
 	SortResults(results)
-	return RemoveDuplicates(results)
+	results = RemoveDuplicates(results)
+	addHighlights(results, terms)
+	return results
 }
@@ -0,0 +1,156 @@
+package search
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Suggestion is a query that may be meant instead of one that found
+// nothing, with the corrections that make it.
+type Suggestion struct {
+	Query       string       `json:"query"`
+	Corrections []Correction `json:"corrections"`
+}
+
+// Correction is a word of a query that matches nothing and the closest
+// names, members and cities to it, closest first.
+type Correction struct {
+	Word  string   `json:"word"`
+	Terms []string `json:"terms"`
+}
+
+// maxSuggestions is how many terms a correction proposes.
+const maxSuggestions = 3
+
+// suggestEdits is how far a term may be from a word of n letters to be
+// suggested: further than searches tolerate, since those found nothing.
+func suggestEdits(n int) int {
+	return maxEdits(n) + 1
+}
+
+// Suggest proposes a correction of query when it finds nothing: each word
+// of its text clauses that matches nothing on its own, or only within a
+// few typos, is replaced by the closest artist name, member or location
+// word, by edit distance, then by how often the term occurs. It returns
+// nil when the query finds results, is invalid, or has no word to correct.
+func (ix *Index) Suggest(query string) *Suggestion {
+	q, err := Parse(query)
+	if err != nil || len(ix.Run(q)) > 0 {
+		return nil
+	}
+	type replacement struct {
+		start, end int // in runes of query
+		term       string
+	}
+	var replacements []replacement
+	s := &Suggestion{Corrections: []Correction{}}
+	runes := []rune(query)
+	for _, c := range q.Clauses {
+		if c.Negate || c.Range != nil || !correctable(c.Field) {
+			continue
+		}
+		// The clause's text is in the query after its column, quoted or not.
+		at := c.Column - 1
+		for _, word := range textWords(c.Text) {
+			start := indexRunes(runes, []rune(word), at)
+			if start < 0 {
+				break
+			}
+			at = start + len([]rune(word))
+			if ix.matchesAsTyped(word) {
+				continue
+			}
+			terms := ix.closestTerms(normalize(word))
+			if len(terms) == 0 {
+				continue
+			}
+			s.Corrections = append(s.Corrections, Correction{Word: word, Terms: terms})
+			replacements = append(replacements, replacement{start, at, terms[0]})
+		}
+	}
+	if len(replacements) == 0 {
+		return nil
+	}
+	// Replace from the end, so the positions before stay right.
+	for i := len(replacements) - 1; i >= 0; i-- {
+		r := replacements[i]
+		runes = append(runes[:r.start], append([]rune(r.term), runes[r.end:]...)...)
+	}
+	s.Query = string(runes)
+	return s
+}
+
+// matchesAsTyped reports whether word matches something other than within
+// a few typos.
+func (ix *Index) matchesAsTyped(word string) bool {
+	for _, r := range ix.SearchAll(word) {
+		if r.Method != MethodFuzzy {
+			return true
+		}
+	}
+	return false
+}
+
+// correctable reports whether clauses of field are text to correct.
+func correctable(field string) bool {
+	switch field {
+	case "", "artist", "member", "location", "country":
+		return true
+	}
+	return false
+}
+
+// textWords splits a clause's text into words, as typed.
+func textWords(s string) []string {
+	return strings.FieldsFunc(s, func(r rune) bool {
+		return unicode.IsSpace(r) || isSeparator(r)
+	})
+}
+
+// indexRunes returns the position of sub in s from position from on, or -1.
+func indexRunes(s, sub []rune, from int) int {
+	for i := from; i+len(sub) <= len(s); i++ {
+		if string(s[i:i+len(sub)]) == string(sub) {
+			return i
+		}
+	}
+	return -1
+}
+
+// closestTerms returns the names, members and location words closest to
+// a normalized word.
+func (ix *Index) closestTerms(word string) []string {
+	query := []rune(word)
+	if len(query) < 3 {
+		return nil
+	}
+	max := suggestEdits(len(query))
+	type candidate struct {
+		term      string
+		distance  int
+		frequency int
+	}
+	var candidates []candidate
+	ix.fuzzy.near(len(query), max, func(lt lengthTerm) {
+		if d := editDistance(query, lt.runes, max); d <= max {
+			t := ix.fuzzy.list[lt.id]
+			candidates = append(candidates, candidate{t.text, d, len(t.postings)})
+		}
+	})
+	sort.Slice(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if a.distance != b.distance {
+			return a.distance < b.distance
+		}
+		if a.frequency != b.frequency {
+			return a.frequency > b.frequency
+		}
+		return a.term < b.term
+	})
+	var terms []string
+	for i := 0; i < len(candidates) && i < maxSuggestions; i++ {
+		terms = append(terms, candidates[i].term)
+	}
+	return terms
+}
@@ -0,0 +1,50 @@
+package search
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSuggest(t *testing.T) {
+	ix := queryFixture()
+	tests := []struct {
+		query string
+		want  string // the suggested query, "" for none
+	}{
+		{"qeeun", "queen"},
+		{"member:freddy mercuri", "member:freddie mercury"},
+		{`"brain may" -beatles`, `"brian may" -beatles`},
+		{"lverpol bitles", "liverpool beatles"},
+		{"metalica", ""},        // found within a typo
+		{"queen", ""},           // finds results
+		{"queen beatles", ""},   // both words match, only not together
+		{"xyzzyq", ""},          // nothing close
+		{"year:1800..1900", ""}, // no text
+		{`queen "unclosed`, ""}, // invalid
+	}
+	for _, tt := range tests {
+		s := ix.Suggest(tt.query)
+		got := ""
+		if s != nil {
+			got = s.Query
+		}
+		if got != tt.want {
+			t.Errorf("Suggest(%q) = %q, want %q", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestSuggestCorrections(t *testing.T) {
+	ix := queryFixture()
+	s := ix.Suggest("Jhonn")
+	if s == nil {
+		t.Fatal("Suggest(Jhonn) = nil")
+	}
+	want := []Correction{{Word: "Jhonn", Terms: []string{"john"}}}
+	if !reflect.DeepEqual(s.Corrections, want) {
+		t.Errorf("Corrections = %+v, want %+v", s.Corrections, want)
+	}
+	if results, _ := ix.Query(s.Query); len(results) == 0 {
+		t.Errorf("the suggestion %q finds nothing", s.Query)
+	}
+}
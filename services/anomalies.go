@@ -0,0 +1,134 @@
+package services
+
+import (
+	"fmt"
+	"groupie-tracker/api"
+	"groupie-tracker/geo"
+	"groupie-tracker/models"
+	"time"
+)
+
+// maxTravelKmPerDay is the most an artist is assumed to travel between two
+// shows per day in between. It allows a long flight on a rest day but not
+// a different continent every night.
+var maxTravelKmPerDay = 2000.0
+
+// Kinds of travel anomalies.
+const (
+	anomalySpeed         = "speed"
+	anomalyDoubleBooking = "double-booking"
+)
+
+const dateLayout = "02-01-2006" // how the upstream API writes dates
+
+// TravelAnomalies checks consecutive concerts, as returned by Concerts, for
+// travel that can't have happened: two different places on the same local
+// date, or more than maxTravelKmPerDay per day between shows. The days
+// between shows are counted from local midnight to local midnight, so a
+// flight east loses the hours it crosses; concerts without a timezone are
+// taken to be in UTC. Locations missing from coords can only be checked for
+// double bookings.
+func TravelAnomalies(concerts []models.Concert, coords map[string]models.Coordinates) []models.TravelAnomaly {
+	var anomalies []models.TravelAnomaly
+	for i := 1; i < len(concerts); i++ {
+		from, to := concerts[i-1], concerts[i]
+		if from.Location == to.Location {
+			continue
+		}
+		fromCoord, okFrom := coords[from.Location]
+		toCoord, okTo := coords[to.Location]
+		km := 0.0
+		if okFrom && okTo {
+			km = geo.DistanceKm(fromCoord.Lat, fromCoord.Lon, toCoord.Lat, toCoord.Lon)
+		}
+
+		anomaly := models.TravelAnomaly{From: from.Location, To: to.Location, FromDate: from.Date, ToDate: to.Date, Km: km}
+		switch {
+		case from.Date.Equal(to.Date):
+			anomaly.Kind = anomalyDoubleBooking
+		case okFrom && okTo && !travelPossible(km, from, to):
+			anomaly.Kind = anomalySpeed
+		default:
+			continue
+		}
+		anomaly.Hint = swapHint(from, to, km, okFrom && okTo)
+		anomalies = append(anomalies, anomaly)
+	}
+	return anomalies
+}
+
+// travelPossible reports whether km can be covered between two shows, in
+// the time between the starts of their local days.
+func travelPossible(km float64, a, b models.Concert) bool {
+	startA, _ := ConcertDay(a)
+	startB, _ := ConcertDay(b)
+	days := startB.Sub(startA).Hours() / 24
+	if days < 0 {
+		days = -days
+	}
+	return days > 0 && km/days <= maxTravelKmPerDay
+}
+
+// swapHint suggests which date may have its day and month swapped: the
+// first one whose swapped form makes the trip possible.
+func swapHint(from, to models.Concert, km float64, known bool) string {
+	for _, c := range []struct {
+		concert, other models.Concert
+	}{{to, from}, {from, to}} {
+		swapped, ok := swapDayMonth(c.concert.Date)
+		if !ok {
+			continue
+		}
+		moved := c.concert
+		moved.Date = swapped
+		if (known && travelPossible(km, moved, c.other)) || (!known && !swapped.Equal(c.other.Date)) {
+			return fmt.Sprintf("%s in %s may have day and month swapped (%s)",
+				c.concert.Date.Format(dateLayout), c.concert.Location, swapped.Format(dateLayout))
+		}
+	}
+	return ""
+}
+
+// swapDayMonth returns t with day and month exchanged, if that is a
+// different valid date.
+func swapDayMonth(t time.Time) (time.Time, bool) {
+	day, month := t.Day(), int(t.Month())
+	if day == month || day > 12 {
+		return time.Time{}, false
+	}
+	return time.Date(t.Year(), time.Month(day), month, 0, 0, 0, 0, t.Location()), true
+}
+
+// AllTravelAnomalies checks every artist using only coordinates already in
+// the cache, so building the report never triggers lookups. Artists without
+// anomalies are left out.
+func AllTravelAnomalies() []models.ArtistAnomalies {
+	var report []models.ArtistAnomalies
+	for _, artist := range api.All_Artists {
+		relations, err := GetRelationsByID(artist.ID)
+		if err != nil {
+			continue
+		}
+		coords := CachedCoordinates(relations.SortedLocations)
+		concerts := Concerts(relations)
+		LocalizeConcerts(concerts, coords)
+		if anomalies := TravelAnomalies(concerts, coords); len(anomalies) > 0 {
+			report = append(report, models.ArtistAnomalies{ArtistID: artist.ID, ArtistName: artist.Name, Anomalies: anomalies})
+		}
+	}
+	return report
+}
+
+// CachedCoordinates returns the cached coordinates of locations without
+// looking up the missing ones.
+func CachedCoordinates(locations []string) map[string]models.Coordinates {
+	coords := make(map[string]models.Coordinates, len(locations))
+	geoMutex.RLock()
+	defer geoMutex.RUnlock()
+	for _, loc := range locations {
+		if entry, ok := geoCache[loc]; ok && !entry.NotFound {
+			coords[loc] = entry.Coordinates
+		}
+	}
+	return coords
+}
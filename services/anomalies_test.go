@@ -0,0 +1,110 @@
+package services
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"groupie-tracker/api"
+	"groupie-tracker/models"
+)
+
+func TestTravelAnomalies(t *testing.T) {
+	day := func(d int, m time.Month, y int) time.Time { return time.Date(y, m, d, 0, 0, 0, 0, time.UTC) }
+	coords := map[string]models.Coordinates{
+		"Paris, France":    {Lat: 48.8566, Lon: 2.3522},
+		"Berlin, Germany":  {Lat: 52.52, Lon: 13.405},
+		"Tokyo, Japan":     {Lat: 35.6762, Lon: 139.6503},
+		"Los Angeles, USA": {Lat: 34.0522, Lon: -118.2437},
+		"Chicago, USA":     {Lat: 41.8781, Lon: -87.6298},
+		"New York, USA":    {Lat: 40.7128, Lon: -74.0060},
+		"London, UK":       {Lat: 51.5074, Lon: -0.1278},
+	}
+	tests := []struct {
+		name     string
+		concerts []models.Concert
+		kind     string
+		hint     string
+	}{
+		{"plausible trip", []models.Concert{
+			{Location: "Paris, France", Date: day(1, 3, 2020)},
+			{Location: "Berlin, Germany", Date: day(2, 3, 2020)},
+		}, "", ""},
+		{"two continents overnight, swapped date", []models.Concert{
+			{Location: "Paris, France", Date: day(1, 3, 2020)},
+			{Location: "Tokyo, Japan", Date: day(2, 3, 2020)},
+		}, anomalySpeed, "02-03-2020 in Tokyo, Japan may have day and month swapped (03-02-2020)"},
+		{"two cities on one date", []models.Concert{
+			{Location: "Berlin, Germany", Date: day(15, 3, 2020)},
+			{Location: "Paris, France", Date: day(15, 3, 2020)},
+		}, anomalyDoubleBooking, ""},
+		{"consecutive days eastwards across one zone", []models.Concert{
+			{Location: "Chicago, USA", Date: day(1, 3, 2019), TimeZone: "America/Chicago"},
+			{Location: "New York, USA", Date: day(2, 3, 2019), TimeZone: "America/New_York"},
+		}, "", ""},
+		{"consecutive days from London to Berlin", []models.Concert{
+			{Location: "London, UK", Date: day(10, 3, 2019), TimeZone: "Europe/London"},
+			{Location: "Berlin, Germany", Date: day(11, 3, 2019), TimeZone: "Europe/Berlin"},
+		}, "", ""},
+		{"the date line shortens the night", []models.Concert{
+			{Location: "Los Angeles, USA", Date: day(1, 3, 2020), TimeZone: "America/Los_Angeles"},
+			{Location: "Tokyo, Japan", Date: day(2, 3, 2020), TimeZone: "Asia/Tokyo"},
+		}, anomalySpeed, "02-03-2020 in Tokyo, Japan may have day and month swapped (03-02-2020)"},
+		{"unknown coordinates are only checked for double bookings", []models.Concert{
+			{Location: "Atlantis, Nowhere", Date: day(1, 3, 2020)},
+			{Location: "Tokyo, Japan", Date: day(2, 3, 2020)},
+		}, "", ""},
+	}
+	for _, tt := range tests {
+		got := TravelAnomalies(tt.concerts, coords)
+		if tt.kind == "" {
+			if len(got) != 0 {
+				t.Errorf("%s: unexpected anomalies %+v", tt.name, got)
+			}
+			continue
+		}
+		if len(got) != 1 || got[0].Kind != tt.kind || got[0].Hint != tt.hint {
+			t.Errorf("%s: got %+v, want one %s anomaly with hint %q", tt.name, got, tt.kind, tt.hint)
+		}
+	}
+}
+
+func TestSwapDayMonth(t *testing.T) {
+	if got, ok := swapDayMonth(time.Date(2020, 3, 2, 0, 0, 0, 0, time.UTC)); !ok || got.Month() != 2 || got.Day() != 3 {
+		t.Errorf("swapDayMonth(02-03-2020) = %v, %v", got, ok)
+	}
+	for _, d := range []time.Time{
+		time.Date(2020, 3, 3, 0, 0, 0, 0, time.UTC),  // same either way
+		time.Date(2020, 3, 25, 0, 0, 0, 0, time.UTC), // no 25th month
+	} {
+		if _, ok := swapDayMonth(d); ok {
+			t.Errorf("swapDayMonth(%s) should fail", d.Format(dateLayout))
+		}
+	}
+}
+
+func TestAllTravelAnomaliesUsesCacheOnly(t *testing.T) {
+	defer setupTestData()()
+	defer setupCache(t)()
+	calls := 0
+	defer setupGeocoder(roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		calls++
+		return geoResponse(http.StatusOK, `[]`, nil), nil
+	}))()
+	api.All_Artists = []models.Artists{{ID: 1, Name: "Queen"}}
+	api.All_Relations = []models.Relations{{ID: 1, DatesLocations: map[string][]string{
+		"paris-france": {"01-03-2020"},
+		"tokyo-japan":  {"02-03-2020"},
+	}}}
+	geoCache["Paris, France"] = geoEntry{Coordinates: models.Coordinates{Lat: 48.8566, Lon: 2.3522}}
+	geoCache["Tokyo, Japan"] = geoEntry{Coordinates: models.Coordinates{Lat: 35.6762, Lon: 139.6503}}
+
+	report := AllTravelAnomalies()
+	if len(report) != 1 || report[0].ArtistName != "Queen" || !strings.Contains(report[0].Anomalies[0].Hint, "Tokyo") {
+		t.Errorf("AllTravelAnomalies() = %+v", report)
+	}
+	if calls != 0 {
+		t.Errorf("report looked up %d locations", calls)
+	}
+}
@@ -0,0 +1,61 @@
+package services
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"groupie-tracker/api"
+	"groupie-tracker/geo"
+	"groupie-tracker/models"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ConcertUID identifies a concert in calendar feeds. It only depends on the
+// artist, location and date, so re-exporting updates events instead of
+// duplicating them.
+func ConcertUID(artistID int, c models.Concert) string {
+	sum := sha1.Sum([]byte(strings.ToLower(c.Location)))
+	return c.Date.Format("20060102") + "-" + hex.EncodeToString(sum[:6]) + "-" + strconv.Itoa(artistID) + "@groupie-tracker"
+}
+
+// ConcertsWhere lists the concerts of every artist at a matching location,
+// in chronological order.
+func ConcertsWhere(match func(location string) bool) []models.ArtistConcert {
+	var list []models.ArtistConcert
+	for _, artist := range api.All_Artists {
+		relations, err := GetRelationsByID(artist.ID)
+		if err != nil {
+			continue
+		}
+		for _, c := range Concerts(relations) {
+			if match(c.Location) {
+				list = append(list, models.ArtistConcert{ArtistID: artist.ID, ArtistName: artist.Name, Concert: c})
+			}
+		}
+	}
+	sort.SliceStable(list, func(i, j int) bool { return list[i].Date.Before(list[j].Date) })
+	return list
+}
+
+// AtLocation matches a formatted location name, ignoring case.
+func AtLocation(name string) func(string) bool {
+	name = strings.TrimSpace(name)
+	return func(location string) bool {
+		return strings.EqualFold(location, name)
+	}
+}
+
+// InCountry matches locations in the named country. Unknown country names
+// match nothing.
+func InCountry(name string) func(string) bool {
+	country, ok := geo.LookupCountry(name)
+	return func(location string) bool {
+		if !ok {
+			return false
+		}
+		_, countryName := geo.SplitLocation(location)
+		c, found := geo.LookupCountry(countryName)
+		return found && c.Code == country.Code
+	}
+}
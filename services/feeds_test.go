@@ -0,0 +1,42 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"groupie-tracker/api"
+	"groupie-tracker/models"
+)
+
+func TestConcertUID(t *testing.T) {
+	c := models.Concert{Location: "Paris, France", Date: time.Date(2020, 3, 1, 0, 0, 0, 0, time.UTC)}
+	uid := ConcertUID(1, c)
+	if uid != ConcertUID(1, c) {
+		t.Error("UID is not stable")
+	}
+	other := c
+	other.Location = "Lyon, France"
+	if uid == ConcertUID(2, c) || uid == ConcertUID(1, other) {
+		t.Error("different concerts share a UID")
+	}
+}
+
+func TestConcertsWhere(t *testing.T) {
+	defer setupTestData()()
+	api.All_Artists = []models.Artists{{ID: 1, Name: "Queen"}, {ID: 2, Name: "Pink Floyd"}}
+	api.All_Relations = []models.Relations{
+		{ID: 1, DatesLocations: map[string][]string{"paris-france": {"02-03-2020"}, "london-uk": {"01-03-2020"}}},
+		{ID: 2, DatesLocations: map[string][]string{"lyon-france": {"01-01-2020"}}},
+	}
+
+	inFrance := ConcertsWhere(InCountry("france"))
+	if len(inFrance) != 2 || inFrance[0].ArtistName != "Pink Floyd" || inFrance[1].Location != "Paris, France" {
+		t.Errorf("ConcertsWhere(InCountry(france)) = %+v", inFrance)
+	}
+	if got := ConcertsWhere(AtLocation("london, uk")); len(got) != 1 || got[0].ArtistID != 1 {
+		t.Errorf("ConcertsWhere(AtLocation(london, uk)) = %+v", got)
+	}
+	if got := ConcertsWhere(InCountry("Atlantis")); len(got) != 0 {
+		t.Errorf("unknown country matched %+v", got)
+	}
+}
@@ -0,0 +1,161 @@
+package services
+
+import (
+	"groupie-tracker/api"
+	"groupie-tracker/models"
+	"sort"
+	"sync/atomic"
+)
+
+// filterData is what filtering needs from the loaded data, collected once
+// per load: reading the relations processes them in place, which
+// concurrent requests must not do.
+type filterData struct {
+	options models.FilterOptions
+	played  map[int]map[string]bool // the formatted locations of each artist's concerts
+}
+
+var currentFilters atomic.Pointer[filterData]
+
+// RebuildFilters collects the filter options and the locations of each
+// artist from the loaded data, for CurrentFilterOptions and MatchesFilter.
+// Call it whenever the data is (re)loaded, as the search index is rebuilt;
+// requests meanwhile keep using the previous ones.
+func RebuildFilters() {
+	d := &filterData{options: ArtistFilterOptions(), played: make(map[int]map[string]bool)}
+	for _, artist := range api.All_Artists {
+		if relations, err := GetRelationsByID(artist.ID); err == nil {
+			locations := make(map[string]bool, len(relations.DatesLocations))
+			for loc := range relations.DatesLocations {
+				locations[loc] = true
+			}
+			d.played[artist.ID] = locations
+		}
+	}
+	currentFilters.Store(d)
+}
+
+// CurrentFilterOptions returns the options collected by the last
+// RebuildFilters, or none before the first.
+func CurrentFilterOptions() models.FilterOptions {
+	if d := currentFilters.Load(); d != nil {
+		return d.options
+	}
+	return models.FilterOptions{}
+}
+
+// FilterActive reports whether f narrows anything down.
+func FilterActive(f models.ArtistFilter) bool {
+	return f.CreationFrom != 0 || f.CreationTo != 0 || f.AlbumFrom != 0 || f.AlbumTo != 0 ||
+		len(f.Members) > 0 || len(f.Locations) > 0
+}
+
+// FilterArtists returns the artists matching every criterion of f, in
+// their original order.
+func FilterArtists(artists []models.Artists, f models.ArtistFilter) []models.Artists {
+	filtered := []models.Artists{}
+	for _, artist := range artists {
+		if MatchesFilter(artist, f) {
+			filtered = append(filtered, artist)
+		}
+	}
+	return filtered
+}
+
+// MatchesFilter reports whether an artist matches every criterion of f.
+// Locations are those collected by the last RebuildFilters.
+func MatchesFilter(artist models.Artists, f models.ArtistFilter) bool {
+	if !inYears(artist.CreationDate, f.CreationFrom, f.CreationTo) {
+		return false
+	}
+	if f.AlbumFrom != 0 || f.AlbumTo != 0 {
+		year, ok := albumYear(artist)
+		if !ok || !inYears(year, f.AlbumFrom, f.AlbumTo) {
+			return false
+		}
+	}
+	if len(f.Members) > 0 && !containsInt(f.Members, len(artist.Members)) {
+		return false
+	}
+	if len(f.Locations) > 0 {
+		d := currentFilters.Load()
+		if d == nil {
+			return false
+		}
+		played := false
+		for _, loc := range f.Locations {
+			if d.played[artist.ID][loc] {
+				played = true
+				break
+			}
+		}
+		if !played {
+			return false
+		}
+	}
+	return true
+}
+
+// ArtistFilterOptions collects the ranges, member counts and locations of
+// the loaded artists. Requests use the ones RebuildFilters collected.
+func ArtistFilterOptions() models.FilterOptions {
+	var opts models.FilterOptions
+	counts := make(map[int]bool)
+	locations := make(map[string]bool)
+	for _, artist := range api.All_Artists {
+		opts.CreationMin, opts.CreationMax = widen(opts.CreationMin, opts.CreationMax, artist.CreationDate)
+		if year, ok := albumYear(artist); ok {
+			opts.AlbumMin, opts.AlbumMax = widen(opts.AlbumMin, opts.AlbumMax, year)
+		}
+		counts[len(artist.Members)] = true
+		if relations, err := GetRelationsByID(artist.ID); err == nil {
+			for loc := range relations.DatesLocations {
+				locations[loc] = true
+			}
+		}
+	}
+	for n := range counts {
+		opts.MemberCounts = append(opts.MemberCounts, n)
+	}
+	sort.Ints(opts.MemberCounts)
+	for loc := range locations {
+		opts.Locations = append(opts.Locations, loc)
+	}
+	sort.Strings(opts.Locations)
+	return opts
+}
+
+// albumYear is the year of the artist's first album.
+func albumYear(artist models.Artists) (int, bool) {
+	t, err := parseDate(artist.FirstAlbum)
+	if err != nil {
+		return 0, false
+	}
+	return t.Year(), true
+}
+
+// inYears reports whether year is within from and to, either of which may
+// be 0 for no bound.
+func inYears(year, from, to int) bool {
+	return (from == 0 || year >= from) && (to == 0 || year <= to)
+}
+
+// widen extends the range min..max, where 0 means empty, to include v.
+func widen(min, max, v int) (int, int) {
+	if min == 0 || v < min {
+		min = v
+	}
+	if max == 0 || v > max {
+		max = v
+	}
+	return min, max
+}
+
+func containsInt(list []int, v int) bool {
+	for _, n := range list {
+		if n == v {
+			return true
+		}
+	}
+	return false
+}
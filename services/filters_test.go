@@ -0,0 +1,101 @@
+package services
+
+import (
+	"groupie-tracker/api"
+	"groupie-tracker/models"
+	"reflect"
+	"testing"
+)
+
+// setupFilterData loads three artists and collects their filters, which
+// the returned function restores.
+func setupFilterData() func() {
+	previous := currentFilters.Load()
+	api.All_Artists = []models.Artists{
+		{ID: 1, Name: "Queen", Members: []string{"a", "b", "c", "d"}, CreationDate: 1970, FirstAlbum: "14-12-1973"},
+		{ID: 2, Name: "Bob Dylan", Members: []string{"a"}, CreationDate: 1961, FirstAlbum: "19-03-1962"},
+		{ID: 3, Name: "Arctic Monkeys", Members: []string{"a", "b", "c", "d"}, CreationDate: 2002, FirstAlbum: "23-01-2006"},
+	}
+	api.All_Relations = []models.Relations{
+		{ID: 1, DatesLocations: map[string][]string{"london-uk": {"12-07-1986"}, "osaka-japan": {"28-01-2020"}}},
+		{ID: 2, DatesLocations: map[string][]string{"new_york-usa": {"01-01-1965"}}},
+		{ID: 3, DatesLocations: map[string][]string{"london-uk": {"01-06-2014"}}},
+	}
+	RebuildFilters()
+	return func() { currentFilters.Store(previous) }
+}
+
+func filteredIDs(f models.ArtistFilter) []int {
+	ids := []int{}
+	for _, a := range FilterArtists(api.All_Artists, f) {
+		ids = append(ids, a.ID)
+	}
+	return ids
+}
+
+func TestFilterArtists(t *testing.T) {
+	defer setupTestData()()
+	defer setupFilterData()()
+
+	tests := []struct {
+		name   string
+		filter models.ArtistFilter
+		want   []int
+	}{
+		{"no filter", models.ArtistFilter{}, []int{1, 2, 3}},
+		{"created from", models.ArtistFilter{CreationFrom: 1965}, []int{1, 3}},
+		{"created between", models.ArtistFilter{CreationFrom: 1960, CreationTo: 1970}, []int{1, 2}},
+		{"first album until", models.ArtistFilter{AlbumTo: 1973}, []int{1, 2}},
+		{"member counts", models.ArtistFilter{Members: []int{1, 2}}, []int{2}},
+		{"locations", models.ArtistFilter{Locations: []string{"London, UK"}}, []int{1, 3}},
+		{"any location", models.ArtistFilter{Locations: []string{"Osaka, Japan", "New York, USA"}}, []int{1, 2}},
+		{"combined", models.ArtistFilter{Members: []int{4}, Locations: []string{"London, UK"}, AlbumFrom: 2000}, []int{3}},
+		{"nothing", models.ArtistFilter{CreationFrom: 2020}, []int{}},
+	}
+	for _, tt := range tests {
+		if got := filteredIDs(tt.filter); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: FilterArtists = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+	if FilterActive(models.ArtistFilter{}) || !FilterActive(models.ArtistFilter{Members: []int{1}}) {
+		t.Error("FilterActive should only be true for filters that narrow something")
+	}
+}
+
+func TestArtistFilterOptions(t *testing.T) {
+	defer setupTestData()()
+	defer setupFilterData()()
+
+	want := models.FilterOptions{
+		CreationMin: 1961, CreationMax: 2002,
+		AlbumMin: 1962, AlbumMax: 2006,
+		MemberCounts: []int{1, 4},
+		Locations:    []string{"London, UK", "New York, USA", "Osaka, Japan"},
+	}
+	if got := ArtistFilterOptions(); !reflect.DeepEqual(got, want) {
+		t.Errorf("ArtistFilterOptions() = %+v, want %+v", got, want)
+	}
+}
+
+func TestRebuildFilters(t *testing.T) {
+	defer setupTestData()()
+	defer setupFilterData()()
+
+	want := ArtistFilterOptions()
+	if got := CurrentFilterOptions(); !reflect.DeepEqual(got, want) {
+		t.Errorf("CurrentFilterOptions() = %+v, want %+v", got, want)
+	}
+	// Requests keep the collected filters until the next rebuild
+	api.All_Relations = []models.Relations{{ID: 2, DatesLocations: map[string][]string{"paris-france": {"01-01-1970"}}}}
+	london := models.ArtistFilter{Locations: []string{"London, UK"}}
+	if got := filteredIDs(london); !reflect.DeepEqual(got, []int{1, 3}) {
+		t.Errorf("before rebuilding, FilterArtists(London) = %v, want [1 3]", got)
+	}
+	RebuildFilters()
+	if got := filteredIDs(london); !reflect.DeepEqual(got, []int{}) {
+		t.Errorf("after rebuilding, FilterArtists(London) = %v, want none", got)
+	}
+	if got := CurrentFilterOptions().Locations; !reflect.DeepEqual(got, []string{"Paris, France"}) {
+		t.Errorf("after rebuilding, locations = %v, want [Paris, France]", got)
+	}
+}
@@ -0,0 +1,172 @@
+package services
+
+import (
+	"errors"
+	"groupie-tracker/api"
+	"groupie-tracker/geo"
+	"groupie-tracker/models"
+	"sort"
+	"strings"
+	"time"
+)
+
+var (
+	errNoSuchCandidate   = errors.New("no such candidate for this location")
+	errUnknownLocation   = errors.New("unknown location")
+	errInvalidCoordinate = errors.New("latitude must be within ±90 and longitude within ±180")
+)
+
+// GeocodeReport lists cached locations whose coordinates were flagged when
+// they were fetched or fall outside the country they name, sorted by
+// location. Each issue carries the alternatives ChooseCandidate accepts.
+func GeocodeReport() []models.GeocodeIssue {
+	geoMutex.RLock()
+	defer geoMutex.RUnlock()
+
+	var issues []models.GeocodeIssue
+	for loc, entry := range geoCache {
+		if entry.NotFound || entry.Pinned {
+			continue
+		}
+		var reason string
+		switch {
+		case entry.Suspicious:
+			reason = "no result inside the named country"
+		case !plausible(loc, entry.Coordinates):
+			reason = "outside the named country"
+		default:
+			continue
+		}
+		issues = append(issues, models.GeocodeIssue{
+			Location:   loc,
+			Current:    entry.Coordinates,
+			Source:     entry.Source,
+			Reason:     reason,
+			Candidates: issueCandidates(loc, entry),
+		})
+	}
+	sort.Slice(issues, func(i, j int) bool { return issues[i].Location < issues[j].Location })
+	return issues
+}
+
+// issueCandidates returns the results the service offered for loc followed
+// by the gazetteer's answer, when it has one.
+func issueCandidates(loc string, entry geoEntry) []models.Coordinates {
+	candidates := append([]models.Coordinates(nil), entry.Candidates...)
+	if place, ok := geo.Resolve(loc); ok {
+		coord := placeCoordinates(place)
+		coord.DisplayName = place.Name + " (gazetteer)"
+		candidates = append(candidates, coord)
+	}
+	return candidates
+}
+
+// ChooseCandidate replaces the cached coordinates of loc with the candidate
+// at index in its report entry. The choice is pinned so background refreshes
+// leave it alone.
+func ChooseCandidate(loc string, index int) error {
+	geoMutex.Lock()
+	entry, ok := geoCache[loc]
+	if !ok || entry.NotFound {
+		geoMutex.Unlock()
+		return errNoSuchCandidate
+	}
+	candidates := issueCandidates(loc, entry)
+	if index < 0 || index >= len(candidates) {
+		geoMutex.Unlock()
+		return errNoSuchCandidate
+	}
+	geoCache[loc] = geoEntry{Coordinates: candidates[index], Source: sourceManual, FetchedAt: time.Now(), Pinned: true}
+	geoMutex.Unlock()
+	requestSave()
+	return nil
+}
+
+// GeocodeEntries lists every location that is cached or used by an artist,
+// with how many artists play there, sorted by location. A non-empty query
+// keeps only locations whose name contains it, ignoring case.
+func GeocodeEntries(query string) []models.GeocodeEntry {
+	usage := locationUsage()
+	query = strings.ToLower(strings.TrimSpace(query))
+
+	geoMutex.RLock()
+	names := make(map[string]bool, len(geoCache)+len(usage))
+	for loc := range geoCache {
+		names[loc] = true
+	}
+	for loc := range usage {
+		names[loc] = true
+	}
+	var list []models.GeocodeEntry
+	for loc := range names {
+		if query != "" && !strings.Contains(strings.ToLower(loc), query) {
+			continue
+		}
+		entry, cached := geoCache[loc]
+		list = append(list, models.GeocodeEntry{
+			Location:    loc,
+			Coordinates: entry.Coordinates,
+			Source:      entry.Source,
+			FetchedAt:   entry.FetchedAt,
+			Missing:     !cached || entry.NotFound,
+			Suspicious:  entry.Suspicious,
+			Pinned:      entry.Pinned,
+			Usage:       usage[loc],
+		})
+	}
+	geoMutex.RUnlock()
+
+	sort.Slice(list, func(i, j int) bool { return list[i].Location < list[j].Location })
+	return list
+}
+
+// locationUsage counts the artists playing at each formatted location.
+func locationUsage() map[string]int {
+	usage := make(map[string]int)
+	for _, rel := range api.All_Relations {
+		for rawLoc := range rel.DatesLocations {
+			usage[formatLocationName(rawLoc)]++
+		}
+	}
+	return usage
+}
+
+// SetCoordinates stores coordinates typed or dragged into place by an admin.
+// They take effect on the next artist page view; pin keeps background
+// refreshes from replacing them.
+func SetCoordinates(loc string, lat, lon float64, pin bool) error {
+	if lat < -90 || lat > 90 || lon < -180 || lon > 180 {
+		return errInvalidCoordinate
+	}
+	if _, used := locationUsage()[loc]; !used {
+		if _, cached := lookupCache(loc); !cached {
+			return errUnknownLocation
+		}
+	}
+	geoMutex.Lock()
+	old := geoCache[loc]
+	coord := models.Coordinates{Lat: lat, Lon: lon, PlaceType: old.PlaceType}
+	if old.NotFound || coord.PlaceType == "" {
+		coord.PlaceType = models.PlaceCity
+	}
+	geoCache[loc] = geoEntry{Coordinates: coord, Source: sourceManual, FetchedAt: time.Now(), Pinned: pin}
+	delete(geoRetries, loc)
+	geoMutex.Unlock()
+	requestSave()
+	return nil
+}
+
+// SetPinned pins or unpins the cached entry of loc.
+func SetPinned(loc string, pinned bool) error {
+	geoMutex.Lock()
+	entry, ok := geoCache[loc]
+	if !ok || entry.NotFound {
+		geoMutex.Unlock()
+		return errUnknownLocation
+	}
+	entry.Pinned = pinned
+	geoCache[loc] = entry
+	geoMutex.Unlock()
+	requestSave()
+	return nil
+}
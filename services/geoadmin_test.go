@@ -0,0 +1,135 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"groupie-tracker/models"
+)
+
+func TestGeocodeReport(t *testing.T) {
+	defer setupCache(t)()
+	geoCache["Paris, France"] = geoEntry{Coordinates: models.Coordinates{Lat: 48.85, Lon: 2.35}, Source: sourceNominatim}
+	geoCache["Birmingham, USA"] = geoEntry{Coordinates: models.Coordinates{Lat: 52.48, Lon: -1.89}, Source: sourceLegacy}
+	geoCache["Victoria, Germany"] = geoEntry{
+		Coordinates: models.Coordinates{Lat: 48.43, Lon: -123.37},
+		Source:      sourceNominatim,
+		Suspicious:  true,
+		Candidates:  []models.Coordinates{{Lat: 48.43, Lon: -123.37}, {Lat: -37.8, Lon: 144.9}},
+	}
+	geoCache["Atlantis, Nowhere"] = geoEntry{NotFound: true}
+
+	issues := GeocodeReport()
+	if len(issues) != 2 || issues[0].Location != "Birmingham, USA" || issues[1].Location != "Victoria, Germany" {
+		t.Fatalf("GeocodeReport() = %+v", issues)
+	}
+	// the gazetteer's answer follows the service's candidates
+	if n := len(issues[1].Candidates); n != 3 || issues[1].Candidates[2].PlaceType != models.PlaceCountry {
+		t.Errorf("candidates = %+v", issues[1].Candidates)
+	}
+}
+
+func TestChooseCandidate(t *testing.T) {
+	defer setupCache(t)()
+	geoCache["Victoria, Germany"] = geoEntry{
+		Coordinates: models.Coordinates{Lat: 48.43, Lon: -123.37},
+		Source:      sourceNominatim,
+		Suspicious:  true,
+		Candidates:  []models.Coordinates{{Lat: 48.43, Lon: -123.37}, {Lat: -37.8, Lon: 144.9}},
+	}
+
+	if err := ChooseCandidate("Victoria, Germany", 5); err == nil {
+		t.Error("expected an error for an out of range candidate")
+	}
+	if err := ChooseCandidate("Victoria, Germany", 1); err != nil {
+		t.Fatalf("ChooseCandidate returned %v", err)
+	}
+	got := geoCache["Victoria, Germany"]
+	if got.Lat != -37.8 || got.Source != sourceManual || got.Suspicious {
+		t.Errorf("entry = %+v", got)
+	}
+	if len(GeocodeReport()) != 0 {
+		t.Error("a manual choice should leave the report")
+	}
+
+	// later lookups don't undo the choice
+	recordLookup("Victoria, Germany", geoEntry{Coordinates: models.Coordinates{Lat: 1, Lon: 1}, Source: sourceNominatim}, nil)
+	if geoCache["Victoria, Germany"].Lat != -37.8 {
+		t.Error("manual entry was overwritten")
+	}
+}
+
+func TestGeocodeEntries(t *testing.T) {
+	defer setupCache(t)()
+	defer setupRelations("paris-france", "berlin-germany")()
+	geoCache["Paris, France"] = geoEntry{Coordinates: models.Coordinates{Lat: 48.85, Lon: 2.35}, Source: sourceNominatim}
+	geoCache["Oslo, Norway"] = geoEntry{Coordinates: models.Coordinates{Lat: 59.91, Lon: 10.75}, Source: sourceLegacy}
+
+	entries := GeocodeEntries("")
+	if len(entries) != 3 {
+		t.Fatalf("GeocodeEntries() returned %d entries, want 3", len(entries))
+	}
+	berlin, oslo, paris := entries[0], entries[1], entries[2]
+	if !berlin.Missing || berlin.Usage != 1 {
+		t.Errorf("Berlin = %+v, want a missing entry used once", berlin)
+	}
+	if oslo.Usage != 0 || paris.Usage != 1 || paris.Coordinates.Lat != 48.85 {
+		t.Errorf("Oslo = %+v, Paris = %+v", oslo, paris)
+	}
+	if got := GeocodeEntries("PAR"); len(got) != 1 || got[0].Location != "Paris, France" {
+		t.Errorf("GeocodeEntries(\"PAR\") = %+v", got)
+	}
+}
+
+func TestSetCoordinates(t *testing.T) {
+	defer setupCache(t)()
+	defer setupRelations("berlin-germany")()
+	geoRetries["Berlin, Germany"] = retryState{Attempts: 3}
+
+	if err := SetCoordinates("Berlin, Germany", 91, 0, true); err == nil {
+		t.Error("expected an error for an invalid latitude")
+	}
+	if err := SetCoordinates("Atlantis, Nowhere", 1, 1, true); err == nil {
+		t.Error("expected an error for an unknown location")
+	}
+	if err := SetCoordinates("Berlin, Germany", 52.52, 13.405, true); err != nil {
+		t.Fatalf("SetCoordinates returned %v", err)
+	}
+	// the correction is served straight away
+	if got := Geocode([]string{"Berlin, Germany"})["Berlin, Germany"]; got.Lat != 52.52 {
+		t.Errorf("Geocode after correction = %+v", got)
+	}
+	if _, queued := geoRetries["Berlin, Germany"]; queued {
+		t.Error("corrected location is still waiting for a retry")
+	}
+}
+
+func TestPinnedEntriesAreNotRefreshed(t *testing.T) {
+	defer setupCache(t)()
+	defer setupJobs()()
+	defer setupRelations("berlin-germany")()
+	calls := 0
+	defer setupGeocoder(roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		calls++
+		return geoResponse(http.StatusOK, `[{"lat":"1","lon":"1"}]`, nil), nil
+	}))()
+	old := time.Now().Add(-2 * geoCacheTTL)
+	geoCache["Berlin, Germany"] = geoEntry{Coordinates: models.Coordinates{Lat: 52.52, Lon: 13.405}, Source: sourceNominatim, FetchedAt: old}
+	if err := SetPinned("Berlin, Germany", true); err != nil {
+		t.Fatalf("SetPinned returned %v", err)
+	}
+
+	StartGeocodeJob(context.Background()).Wait()
+	if calls != 0 || geoCache["Berlin, Germany"].Lat != 52.52 {
+		t.Errorf("pinned entry was refreshed: %d calls, entry %+v", calls, geoCache["Berlin, Germany"])
+	}
+
+	SetPinned("Berlin, Germany", false)
+	StartGeocodeJob(context.Background()).Wait()
+	waitForFlights()
+	if calls != 1 {
+		t.Errorf("unpinned stale entry was looked up %d times, want 1", calls)
+	}
+}
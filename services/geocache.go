@@ -0,0 +1,422 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"groupie-tracker/geo"
+	"groupie-tracker/models"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// geoCacheVersion is written in the cache file header. Bump it whenever the
+// entry format changes and teach decodeCache how to read the old one.
+//
+//	0: flat {"location": {"lat": "..", "lon": ".."}} map, no header
+//	1: header with entries carrying source and fetch time, string coordinates
+//	2: float coordinates with place metadata
+const geoCacheVersion = 2
+
+const (
+	geoCacheTTL      = 180 * 24 * time.Hour // positive entries are re-validated after this
+	negativeCacheTTL = 24 * time.Hour       // failed lookups are retried after this
+	gazetteerTTL     = 24 * time.Hour       // offline answers are replaced once online
+	migrationMatchKm = 100                  // how close a gazetteer place must be to lend its type
+	retryBaseDelay   = 15 * time.Minute     // first retry of a failed lookup, doubled per attempt
+	retryMaxDelay    = 7 * 24 * time.Hour
+)
+
+// saveDelay lets bursts of updates share one write.
+var saveDelay = 2 * time.Second
+
+// Where a cache entry came from.
+const (
+	sourceNominatim = "nominatim"
+	sourceLegacy    = "legacy" // imported from an unversioned cache file
+	sourceGazetteer = "gazetteer"
+	sourceManual    = "manual" // chosen or corrected by an admin
+)
+
+// geoEntry is a single cached lookup. Negative entries (NotFound) remember
+// that a lookup failed so it isn't repeated on every page view. Suspicious
+// entries lie outside the country they name; Candidates keeps the other
+// results the service offered so an admin can pick the right one. Pinned
+// entries were confirmed by an admin and are never looked up again.
+// Approximate entries are the centre of the country of a city the
+// gazetteer doesn't know.
+type geoEntry struct {
+	models.Coordinates
+	Source      string               `json:"source"`
+	FetchedAt   time.Time            `json:"fetchedAt"`
+	NotFound    bool                 `json:"notFound,omitempty"`
+	Suspicious  bool                 `json:"suspicious,omitempty"`
+	Candidates  []models.Coordinates `json:"candidates,omitempty"`
+	Pinned      bool                 `json:"pinned,omitempty"`
+	Approximate bool                 `json:"approximate,omitempty"`
+}
+
+// stale reports whether the entry should be looked up again.
+func (e geoEntry) stale(now time.Time) bool {
+	switch {
+	case e.Pinned:
+		return false
+	case e.NotFound:
+		return now.Sub(e.FetchedAt) > negativeCacheTTL
+	case e.Source == sourceGazetteer:
+		return now.Sub(e.FetchedAt) > gazetteerTTL
+	}
+	return now.Sub(e.FetchedAt) > geoCacheTTL
+}
+
+// retryState tracks a location whose lookups keep failing, so background
+// jobs retry it with exponential backoff instead of on every run.
+type retryState struct {
+	Attempts  int       `json:"attempts"`
+	NextTry   time.Time `json:"nextTry"`
+	LastError string    `json:"lastError"`
+}
+
+// retryDelay is how long to wait after the given number of failed attempts.
+func retryDelay(attempts int) time.Duration {
+	d := retryBaseDelay
+	for i := 1; i < attempts && d < retryMaxDelay; i++ {
+		d *= 2
+	}
+	if d > retryMaxDelay {
+		d = retryMaxDelay
+	}
+	return d
+}
+
+// geoCacheFile is the on-disk layout of the cache. Retries was added
+// without a version bump: older readers ignore it and lose only the backoff.
+type geoCacheFile struct {
+	Version int                   `json:"version"`
+	Entries map[string]geoEntry   `json:"entries"`
+	Retries map[string]retryState `json:"retries,omitempty"`
+}
+
+// memory cache
+var (
+	geoCache   = make(map[string]geoEntry)
+	geoRetries = make(map[string]retryState) // guarded by geoMutex
+	geoMutex   sync.RWMutex
+	cacheFile  = "locations.json" // to store cached locations
+
+	saveMutex  sync.Mutex // serializes writes to cacheFile
+	saveSignal = make(chan struct{}, 1)
+	writerOnce sync.Once
+
+	// savesWanted counts the saves requested and savesDone those covered by
+	// a finished write; saveDone is broadcast after each write.
+	saveState   sync.Mutex
+	saveDone    = sync.NewCond(&saveState)
+	savesWanted int
+	savesDone   int
+)
+
+func loadCache() {
+	info, err := os.Stat(cacheFile)
+	if err != nil {
+		fmt.Println("No cache file found, starting with empty cache.")
+		return
+	}
+	data, err := os.ReadFile(cacheFile)
+	if err != nil {
+		fmt.Printf("Failed to read cache file: %v\n", err)
+		return
+	}
+	entries, version, err := decodeCache(data, info.ModTime())
+	if err != nil {
+		fmt.Printf("Ignoring unreadable cache file: %v\n", err)
+		return
+	}
+	var file struct {
+		Retries map[string]retryState `json:"retries"`
+	}
+	if json.Unmarshal(data, &file) != nil || file.Retries == nil {
+		file.Retries = make(map[string]retryState)
+	}
+
+	geoMutex.Lock()
+	geoCache = entries
+	geoRetries = file.Retries
+	fmt.Printf("Loaded %d locations from cache.\n", len(geoCache))
+	geoMutex.Unlock()
+
+	// upgrade older files in place so the migration only runs once
+	if version != geoCacheVersion {
+		if err := saveCache(); err != nil {
+			fmt.Printf("Failed to upgrade cache file: %v\n", err)
+		} else {
+			fmt.Printf("Upgraded cache file from version %d to %d.\n", version, geoCacheVersion)
+		}
+	}
+}
+
+// legacyCoordinates is how versions 0 and 1 stored positions.
+type legacyCoordinates struct {
+	Lat string `json:"lat"`
+	Lon string `json:"lon"`
+}
+
+// legacyEntry is a version 1 cache entry.
+type legacyEntry struct {
+	legacyCoordinates
+	Source    string    `json:"source"`
+	FetchedAt time.Time `json:"fetchedAt"`
+	NotFound  bool      `json:"notFound,omitempty"`
+}
+
+// decodeCache reads the current format and every older one, returning the
+// entries and the version found in the file. Entries from the unversioned
+// map are dated with the file's modification time since that's the best
+// guess we have.
+func decodeCache(data []byte, modTime time.Time) (map[string]geoEntry, int, error) {
+	var header struct {
+		Version int `json:"version"`
+	}
+	// a flat map fails to decode into the header, which is fine
+	_ = json.Unmarshal(data, &header)
+
+	switch header.Version {
+	case 0:
+		var legacy map[string]legacyCoordinates
+		if err := json.Unmarshal(data, &legacy); err != nil {
+			return nil, 0, err
+		}
+		entries := make(map[string]geoEntry, len(legacy))
+		for loc, old := range legacy {
+			if coord, ok := migrateCoordinates(loc, old); ok {
+				entries[loc] = geoEntry{Coordinates: coord, Source: sourceLegacy, FetchedAt: modTime}
+			}
+		}
+		return entries, 0, nil
+	case 1:
+		var file struct {
+			Entries map[string]legacyEntry `json:"entries"`
+		}
+		if err := json.Unmarshal(data, &file); err != nil {
+			return nil, 1, err
+		}
+		entries := make(map[string]geoEntry, len(file.Entries))
+		for loc, old := range file.Entries {
+			entry := geoEntry{Source: old.Source, FetchedAt: old.FetchedAt, NotFound: old.NotFound}
+			if !old.NotFound {
+				coord, ok := migrateCoordinates(loc, old.legacyCoordinates)
+				if !ok {
+					continue
+				}
+				entry.Coordinates = coord
+			}
+			entries[loc] = entry
+		}
+		return entries, 1, nil
+	case geoCacheVersion:
+		var file geoCacheFile
+		if err := json.Unmarshal(data, &file); err != nil {
+			return nil, geoCacheVersion, err
+		}
+		if file.Entries == nil {
+			file.Entries = make(map[string]geoEntry)
+		}
+		return file.Entries, geoCacheVersion, nil
+	default:
+		return nil, header.Version, fmt.Errorf("unsupported cache version %d", header.Version)
+	}
+}
+
+// migrateCoordinates parses string coordinates from an old cache file. Old
+// files didn't record what kind of place was found, so it is borrowed from
+// the gazetteer when it knows a place of that name close to the position.
+func migrateCoordinates(loc string, old legacyCoordinates) (models.Coordinates, bool) {
+	lat, errLat := strconv.ParseFloat(old.Lat, 64)
+	lon, errLon := strconv.ParseFloat(old.Lon, 64)
+	if errLat != nil || errLon != nil {
+		return models.Coordinates{}, false
+	}
+	coord := models.Coordinates{Lat: lat, Lon: lon}
+	if place, ok := geo.Resolve(loc); ok && geo.DistanceKm(lat, lon, place.Lat, place.Lon) < migrationMatchKm {
+		coord.PlaceType = models.PlaceType(place.Kind)
+	} else if _, countryName := geo.SplitLocation(loc); countryName != "" {
+		// some old lookups only found the country and returned its centroid
+		if c, ok := geo.LookupCountry(countryName); ok && geo.DistanceKm(lat, lon, c.Lat, c.Lon) < migrationMatchKm {
+			coord.PlaceType = models.PlaceCountry
+		}
+	}
+	return coord, true
+}
+
+// saveCache writes the cache to a temporary file and renames it over
+// cacheFile, so a crash mid-write never leaves a truncated cache behind.
+func saveCache() error {
+	saveMutex.Lock()
+	defer saveMutex.Unlock()
+
+	geoMutex.RLock()
+	data, err := json.MarshalIndent(geoCacheFile{Version: geoCacheVersion, Entries: geoCache, Retries: geoRetries}, "", "  ")
+	geoMutex.RUnlock()
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(cacheFile), filepath.Base(cacheFile)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	// clean up the temp file on any failure below; after a successful
+	// rename this is a no-op
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), cacheFile)
+}
+
+// requestSave asks the writer goroutine to persist the cache. Requests made
+// while a save is already pending are merged into it.
+func requestSave() {
+	writerOnce.Do(func() { go cacheWriter() })
+	saveState.Lock()
+	savesWanted++
+	saveState.Unlock()
+	select {
+	case saveSignal <- struct{}{}:
+	default: // a save is already pending
+	}
+}
+
+// waitForSave blocks until every save requested so far has been written.
+func waitForSave() {
+	saveState.Lock()
+	defer saveState.Unlock()
+	for target := savesWanted; savesDone < target; {
+		saveDone.Wait()
+	}
+}
+
+// cacheWriter is the only goroutine that saves the cache in the background.
+// Each write covers the requests made before it starts; later ones signal
+// another round, which is skipped if that write already covered them.
+func cacheWriter() {
+	for range saveSignal {
+		saveState.Lock()
+		pending := savesDone < savesWanted
+		saveState.Unlock()
+		if !pending {
+			continue
+		}
+		time.Sleep(saveDelay)
+		saveState.Lock()
+		covered := savesWanted
+		saveState.Unlock()
+		if err := saveCache(); err != nil {
+			fmt.Printf("Failed to save cache: %v\n", err)
+		}
+		saveState.Lock()
+		savesDone = covered
+		saveState.Unlock()
+		saveDone.Broadcast()
+	}
+}
+
+// lookupCache returns the cached entry for loc, if any.
+func lookupCache(loc string) (geoEntry, bool) {
+	geoMutex.RLock()
+	defer geoMutex.RUnlock()
+	entry, ok := geoCache[loc]
+	return entry, ok
+}
+
+// retryDue reports whether loc may be looked up again by a background job.
+func retryDue(loc string, now time.Time) bool {
+	geoMutex.RLock()
+	defer geoMutex.RUnlock()
+	r, queued := geoRetries[loc]
+	return !queued || !now.Before(r.NextTry)
+}
+
+// provisional reports whether loc holds a gazetteer fallback whose lookup
+// is still waiting for a retry.
+func provisional(loc string) bool {
+	geoMutex.RLock()
+	defer geoMutex.RUnlock()
+	_, queued := geoRetries[loc]
+	return queued && geoCache[loc].Source == sourceGazetteer
+}
+
+// retryQueueLen returns how many locations are waiting for a retry.
+func retryQueueLen() int {
+	geoMutex.RLock()
+	defer geoMutex.RUnlock()
+	return len(geoRetries)
+}
+
+// updateRetries records the outcome of a lookup in the retry queue. The
+// caller holds geoMutex. An open circuit means no lookup was attempted, so
+// it doesn't count against the location.
+func updateRetries(loc string, err error, now time.Time) {
+	switch {
+	case err == nil:
+		delete(geoRetries, loc)
+	case err != errCircuitOpen:
+		r := geoRetries[loc]
+		r.Attempts++
+		r.NextTry = now.Add(retryDelay(r.Attempts))
+		r.LastError = err.Error()
+		geoRetries[loc] = r
+	}
+}
+
+// recordLookup stores the outcome of a lookup and schedules a save.
+// Only definitive "not found" answers are cached negatively; transient
+// errors are left for the next attempt, except that a gazetteer fallback
+// (errFallback) is kept provisionally: it expires after gazetteerTTL and its
+// lookup stays in the retry queue. A failed re-validation never replaces
+// coordinates we already have, a gazetteer fallback doesn't replace
+// coordinates that came from the service, and nothing replaces a pinned entry.
+func recordLookup(loc string, entry geoEntry, err error) {
+	geoMutex.Lock()
+	_, wasQueued := geoRetries[loc]
+	updateRetries(loc, err, time.Now())
+	_, isQueued := geoRetries[loc]
+	old, hasOld := geoCache[loc]
+	keepOld := hasOld && !old.NotFound && old.Source != sourceGazetteer
+	switch {
+	case hasOld && old.Pinned:
+		geoMutex.Unlock()
+		if wasQueued || isQueued {
+			requestSave()
+		}
+		return
+	case err == nil, errors.Is(err, errFallback) && !keepOld:
+		entry.FetchedAt = time.Now()
+		geoCache[loc] = entry
+	case err == errNoCoordinates && (!hasOld || old.NotFound):
+		geoCache[loc] = geoEntry{Source: sourceNominatim, FetchedAt: time.Now(), NotFound: true}
+	default:
+		geoMutex.Unlock()
+		if wasQueued || isQueued {
+			requestSave() // persist the retry queue
+		}
+		return
+	}
+	geoMutex.Unlock()
+	requestSave()
+}
@@ -0,0 +1,259 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"groupie-tracker/models"
+)
+
+// setupCache points the cache at a temporary file and empties it.
+func setupCache(t *testing.T) func() {
+	origFile, origCache, origRetries, origDelay := cacheFile, geoCache, geoRetries, saveDelay
+	cacheFile = filepath.Join(t.TempDir(), "locations.json")
+	geoCache = make(map[string]geoEntry)
+	geoRetries = make(map[string]retryState)
+	saveDelay = 0
+	return func() {
+		// background saves must land in the temp file, not the real cache
+		waitForSave()
+		cacheFile, geoCache, geoRetries, saveDelay = origFile, origCache, origRetries, origDelay
+	}
+}
+
+func TestDecodeCacheLegacy(t *testing.T) {
+	modTime := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	data := []byte(`{"Paris, France": {"lat": "48.85", "lon": "2.35"}, "Bad, Entry": {"lat": "x", "lon": "1"}}`)
+
+	entries, version, err := decodeCache(data, modTime)
+	if err != nil || version != 0 {
+		t.Fatalf("decodeCache returned version %d, %v", version, err)
+	}
+	got := entries["Paris, France"]
+	if got.Lat != 48.85 || got.Source != sourceLegacy || !got.FetchedAt.Equal(modTime) {
+		t.Errorf("legacy entry = %+v", got)
+	}
+	if got.PlaceType != models.PlaceCity {
+		t.Errorf("place type = %q, want it borrowed from the gazetteer", got.PlaceType)
+	}
+	if _, ok := entries["Bad, Entry"]; ok {
+		t.Error("unparseable coordinates should be dropped")
+	}
+}
+
+func TestDecodeCacheVersion1(t *testing.T) {
+	data := []byte(`{"version": 1, "entries": {
+		"Alabama, USA": {"lat": "33.2588817", "lon": "-86.8295337", "source": "nominatim", "fetchedAt": "2025-01-01T00:00:00Z"},
+		"Atlantis, Nowhere": {"lat": "", "lon": "", "source": "nominatim", "fetchedAt": "2025-01-01T00:00:00Z", "notFound": true}
+	}}`)
+
+	entries, version, err := decodeCache(data, time.Now())
+	if err != nil || version != 1 {
+		t.Fatalf("decodeCache returned version %d, %v", version, err)
+	}
+	if got := entries["Alabama, USA"]; got.Lon != -86.8295337 || got.PlaceType != models.PlaceState {
+		t.Errorf("Alabama entry = %+v, want a state centroid", got)
+	}
+	if !entries["Atlantis, Nowhere"].NotFound {
+		t.Error("negative entry lost in migration")
+	}
+}
+
+func TestLoadCacheUpgradesFile(t *testing.T) {
+	defer setupCache(t)()
+	os.WriteFile(cacheFile, []byte(`{"Paris, France": {"lat": "48.85", "lon": "2.35"}}`), 0644)
+
+	loadCache()
+	data, _ := os.ReadFile(cacheFile)
+	if _, version, err := decodeCache(data, time.Now()); err != nil || version != geoCacheVersion {
+		t.Errorf("file on disk is version %d (%v), want %d", version, err, geoCacheVersion)
+	}
+}
+
+func TestDecodeCacheUnknownVersion(t *testing.T) {
+	if _, _, err := decodeCache([]byte(`{"version": 99, "entries": {}}`), time.Now()); err == nil {
+		t.Error("expected an error for an unknown version")
+	}
+}
+
+func TestSaveCacheRoundTrip(t *testing.T) {
+	defer setupCache(t)()
+	fetched := time.Date(2025, 5, 1, 12, 0, 0, 0, time.UTC)
+	geoCache["Paris, France"] = geoEntry{
+		Coordinates: models.Coordinates{Lat: 48.85, Lon: 2.35, PlaceType: models.PlaceCity, BoundingBox: []float64{48.8, 48.9, 2.2, 2.4}},
+		Source:      sourceNominatim,
+		FetchedAt:   fetched,
+	}
+	geoCache["Atlantis, Nowhere"] = geoEntry{Source: sourceNominatim, FetchedAt: fetched, NotFound: true}
+
+	if err := saveCache(); err != nil {
+		t.Fatalf("saveCache returned %v", err)
+	}
+	// only the cache file is left behind, no temp files
+	files, _ := os.ReadDir(filepath.Dir(cacheFile))
+	if len(files) != 1 {
+		t.Errorf("expected 1 file in cache dir, found %d", len(files))
+	}
+
+	geoCache = make(map[string]geoEntry)
+	loadCache()
+	if got := geoCache["Paris, France"]; got.Lon != 2.35 || len(got.BoundingBox) != 4 || !got.FetchedAt.Equal(fetched) {
+		t.Errorf("reloaded entry = %+v", got)
+	}
+	if !geoCache["Atlantis, Nowhere"].NotFound {
+		t.Error("negative entry lost on reload")
+	}
+}
+
+func TestWaitForSaveCoversEveryRequest(t *testing.T) {
+	defer setupCache(t)()
+	saveDelay = time.Millisecond // let requests arrive while a write is pending
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			geoMutex.Lock()
+			geoCache[fmt.Sprintf("City %d, France", i)] = geoEntry{Source: sourceNominatim, FetchedAt: time.Now()}
+			geoMutex.Unlock()
+			requestSave()
+		}(i)
+		if i%5 == 0 {
+			time.Sleep(time.Millisecond)
+		}
+	}
+	wg.Wait()
+	waitForSave()
+
+	data, err := os.ReadFile(cacheFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries, _, err := decodeCache(data, time.Now())
+	if err != nil || len(entries) != 20 {
+		t.Errorf("saved %d entries, %v; want all 20 requested before waitForSave", len(entries), err)
+	}
+}
+
+func TestGeoEntryStale(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name  string
+		entry geoEntry
+		want  bool
+	}{
+		{"fresh", geoEntry{FetchedAt: now.Add(-time.Hour)}, false},
+		{"past TTL", geoEntry{FetchedAt: now.Add(-geoCacheTTL - time.Hour)}, true},
+		{"recent failure", geoEntry{FetchedAt: now.Add(-time.Hour), NotFound: true}, false},
+		{"expired failure", geoEntry{FetchedAt: now.Add(-negativeCacheTTL - time.Hour), NotFound: true}, true},
+	}
+	for _, tt := range tests {
+		if got := tt.entry.stale(now); got != tt.want {
+			t.Errorf("%s: stale() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestGeocodeNegativeCaching(t *testing.T) {
+	defer setupCache(t)()
+	calls := 0
+	defer setupGeocoder(roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		calls++
+		return geoResponse(http.StatusOK, `[]`, nil), nil
+	}))()
+
+	Geocode([]string{"Atlantis, Nowhere"})
+	Geocode([]string{"Atlantis, Nowhere"})
+	if calls != 1 {
+		t.Errorf("service was called %d times, want 1", calls)
+	}
+	if entry, ok := lookupCache("Atlantis, Nowhere"); !ok || !entry.NotFound {
+		t.Errorf("expected a negative entry, got %+v", entry)
+	}
+}
+
+func TestRecordLookupKeepsCoordinates(t *testing.T) {
+	defer setupCache(t)()
+	old := geoEntry{Coordinates: models.Coordinates{Lat: 1, Lon: 2}, Source: sourceLegacy}
+	geoCache["Paris, France"] = old
+
+	// a failed re-validation must not erase what we already know
+	recordLookup("Paris, France", geoEntry{}, errNoCoordinates)
+	if got := geoCache["Paris, France"]; !reflect.DeepEqual(got, old) {
+		t.Errorf("entry changed to %+v", got)
+	}
+}
+
+func TestGeocodeFallsBackToGazetteer(t *testing.T) {
+	defer setupCache(t)()
+	defer setupGeocoder(roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		return nil, errors.New("network unreachable")
+	}))()
+
+	res := Geocode([]string{"Zurich, Switzerland"})
+	if res["Zurich, Switzerland"].Lat != 47.3744 {
+		t.Fatalf("Geocode = %v, want gazetteer coordinates", res)
+	}
+	if entry, _ := lookupCache("Zurich, Switzerland"); entry.Source != sourceGazetteer {
+		t.Errorf("entry source = %q, want %q", entry.Source, sourceGazetteer)
+	}
+	if !provisional("Zurich, Switzerland") {
+		t.Error("the fallback isn't queued for a retry")
+	}
+}
+
+func TestGeocodeFallbackOnlyWhenUnreachable(t *testing.T) {
+	defer setupCache(t)()
+	defer setupGeocoder(roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		if strings.Contains(r.URL.RawQuery, "Zurich") {
+			return geoResponse(http.StatusOK, `[]`, nil), nil
+		}
+		return geoResponse(http.StatusServiceUnavailable, "", nil), nil
+	}))()
+
+	res := Geocode([]string{"Zurich, Switzerland", "Berlin, Germany"})
+	if len(res) != 0 {
+		t.Errorf("Geocode = %v, want nothing from the gazetteer", res)
+	}
+	if entry, _ := lookupCache("Zurich, Switzerland"); !entry.NotFound {
+		t.Errorf("Zurich entry = %+v, want not found", entry)
+	}
+	if _, cached := lookupCache("Berlin, Germany"); cached {
+		t.Error("a service error was cached")
+	}
+}
+
+func TestGeocodeFallbackMarksCountryCentroid(t *testing.T) {
+	defer setupCache(t)()
+	defer setupGeocoder(roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		return nil, errors.New("network unreachable")
+	}))()
+
+	entry, err := resolveLocation(context.Background(), "Qwxzplk, Switzerland")
+	if !errors.Is(err, errFallback) || !errors.Is(err, errNetwork) {
+		t.Fatalf("err = %v, want a fallback after a network error", err)
+	}
+	if !entry.Approximate {
+		t.Errorf("entry = %+v, want approximate", entry)
+	}
+}
+
+func TestRecordLookupGazetteerKeepsServiceCoordinates(t *testing.T) {
+	defer setupCache(t)()
+	old := geoEntry{Coordinates: models.Coordinates{Lat: 1, Lon: 2}, Source: sourceNominatim}
+	geoCache["Paris, France"] = old
+
+	recordLookup("Paris, France", geoEntry{Coordinates: models.Coordinates{Lat: 3, Lon: 4}, Source: sourceGazetteer}, fmt.Errorf("%w: %w", errFallback, errNetwork))
+	if got := geoCache["Paris, France"]; !reflect.DeepEqual(got, old) {
+		t.Errorf("entry changed to %+v", got)
+	}
+}
@@ -0,0 +1,212 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"groupie-tracker/api"
+	"groupie-tracker/models"
+	"sort"
+	"sync"
+	"time"
+)
+
+// geocodeWorkers bounds how many lookups run at once. Network lookups still
+// go through geoLimiter one per second; the extra workers keep gazetteer and
+// cache hits from queueing behind them.
+var geocodeWorkers = 4
+
+// Job states reported in models.GeocodeProgress.
+const (
+	jobIdle      = "idle"
+	jobRunning   = "running"
+	jobDone      = "done"
+	jobCancelled = "cancelled"
+	jobStopped   = "stopped" // the service kept failing, see errCircuitOpen
+)
+
+// GeocodeJob is one run over every location that needs a lookup.
+type GeocodeJob struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	mu       sync.Mutex
+	progress models.GeocodeProgress
+}
+
+var (
+	jobMutex   sync.Mutex
+	currentJob *GeocodeJob
+)
+
+// FillCacheBackground runs a geocoding job over every known location and
+// waits for it to finish.
+func FillCacheBackground() {
+	StartGeocodeJob(context.Background()).Wait()
+}
+
+// StartGeocodeJob starts a job looking up every location that is missing,
+// stale, or due for a retry, unless one is already running, in which case
+// that job is returned. Cancelling ctx or calling CancelGeocodeJob stops it.
+func StartGeocodeJob(ctx context.Context) *GeocodeJob {
+	jobMutex.Lock()
+	defer jobMutex.Unlock()
+	if currentJob != nil && currentJob.running() {
+		return currentJob
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	job := &GeocodeJob{cancel: cancel, done: make(chan struct{})}
+	locs := pendingLocations(time.Now())
+	job.progress = models.GeocodeProgress{State: jobRunning, Total: len(locs), StartedAt: time.Now()}
+	currentJob = job
+	go job.run(ctx, locs)
+	return job
+}
+
+// CancelGeocodeJob stops the running job, if any. Lookups already in flight
+// finish and are cached; nothing new is started.
+func CancelGeocodeJob() {
+	jobMutex.Lock()
+	job := currentJob
+	jobMutex.Unlock()
+	if job != nil {
+		job.cancel()
+	}
+}
+
+// GeocodeStatus reports the progress of the latest job.
+func GeocodeStatus() models.GeocodeProgress {
+	jobMutex.Lock()
+	job := currentJob
+	jobMutex.Unlock()
+
+	progress := models.GeocodeProgress{State: jobIdle}
+	if job != nil {
+		progress = job.snapshot(time.Now())
+	}
+	progress.Retrying = retryQueueLen()
+	return progress
+}
+
+// pendingLocations collects the formatted names of every location in
+// api.All_Relations that needs a lookup, in a stable order.
+func pendingLocations(now time.Time) []string {
+	unique := make(map[string]bool)
+	for _, rel := range api.All_Relations {
+		for rawLoc := range rel.DatesLocations {
+			unique[formatLocationName(rawLoc)] = true
+		}
+	}
+	var locs []string
+	for loc := range unique {
+		if entry, exists := lookupCache(loc); exists && !entry.stale(now) && !provisional(loc) {
+			continue
+		}
+		if !retryDue(loc, now) {
+			continue // failed recently, wait for its backoff
+		}
+		locs = append(locs, loc)
+	}
+	sort.Strings(locs)
+	return locs
+}
+
+func (j *GeocodeJob) run(ctx context.Context, locs []string) {
+	defer close(j.done)
+	defer j.cancel()
+	fmt.Printf("Starting background geocoding of %d locations...\n", len(locs))
+
+	queue := make(chan string)
+	var wg sync.WaitGroup
+	for i := 0; i < geocodeWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for loc := range queue {
+				j.lookup(ctx, loc)
+			}
+		}()
+	}
+feed:
+	for _, loc := range locs {
+		select {
+		case queue <- loc:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(queue)
+	wg.Wait()
+
+	j.mu.Lock()
+	if j.progress.State == jobRunning {
+		j.progress.State = jobDone
+		if ctx.Err() != nil {
+			j.progress.State = jobCancelled
+		}
+	}
+	p := j.progress
+	j.mu.Unlock()
+	fmt.Printf("Geolocalization background update %s: %d/%d done, %d failed.\n", p.State, p.Done, p.Total, p.Failed)
+}
+
+// lookup geocodes a single location and updates the counters. When the
+// service keeps failing the whole job stops; the rest is retried next run.
+func (j *GeocodeJob) lookup(ctx context.Context, loc string) {
+	if ctx.Err() != nil {
+		return
+	}
+	_, err := lookupShared(ctx, loc, true)
+	if errors.Is(err, context.Canceled) {
+		return // the job was cancelled while waiting
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.progress.Done++
+	switch {
+	case err == nil:
+		fmt.Printf("Cached: %s\n", loc)
+	case errors.Is(err, errFallback):
+		j.progress.Failed++
+		fmt.Printf("Cached %s from the gazetteer until a retry: %v\n", loc, err)
+	case errors.Is(err, errCircuitOpen):
+		j.progress.Failed++
+		if j.progress.State == jobRunning {
+			fmt.Printf("Stopping background geocoding: %v\n", err)
+			j.progress.State = jobStopped
+			j.cancel()
+		}
+	default:
+		j.progress.Failed++
+		fmt.Printf("Failed to fetch %s: %v\n", loc, err)
+	}
+}
+
+func (j *GeocodeJob) running() bool {
+	select {
+	case <-j.done:
+		return false
+	default:
+		return true
+	}
+}
+
+// Wait blocks until the job has finished.
+func (j *GeocodeJob) Wait() {
+	<-j.done
+}
+
+// snapshot copies the progress and estimates the time left from the
+// average time per location so far.
+func (j *GeocodeJob) snapshot(now time.Time) models.GeocodeProgress {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	p := j.progress
+	if p.State == jobRunning && p.Done > 0 {
+		perLoc := now.Sub(p.StartedAt) / time.Duration(p.Done)
+		p.ETASeconds = int((perLoc * time.Duration(p.Total-p.Done)).Seconds())
+	}
+	return p
+}
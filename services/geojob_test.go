@@ -0,0 +1,130 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"groupie-tracker/api"
+	"groupie-tracker/models"
+)
+
+// setupRelations makes the job see the given raw locations.
+func setupRelations(locs ...string) func() {
+	orig := api.All_Relations
+	rel := models.Relations{DatesLocations: make(map[string][]string)}
+	for _, loc := range locs {
+		rel.DatesLocations[loc] = []string{"01-01-2020"}
+	}
+	api.All_Relations = []models.Relations{rel}
+	return func() { api.All_Relations = orig }
+}
+
+// setupJobs forgets the previous job so each test starts idle.
+func setupJobs() func() {
+	jobMutex.Lock()
+	orig := currentJob
+	currentJob = nil
+	jobMutex.Unlock()
+	return func() {
+		jobMutex.Lock()
+		currentJob = orig
+		jobMutex.Unlock()
+	}
+}
+
+func TestGeocodeJobProgressAndRetries(t *testing.T) {
+	defer setupCache(t)()
+	defer setupJobs()()
+	defer setupRelations("paris-france", "atlantis-nowhere", "berlin-germany")()
+	defer setupGeocoder(roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		if strings.Contains(r.URL.Query().Get("q"), "Atlantis") {
+			return geoResponse(http.StatusOK, `[]`, nil), nil
+		}
+		return geoResponse(http.StatusOK, `[{"lat":"48.85","lon":"2.35"}]`, nil), nil
+	}))()
+
+	if got := GeocodeStatus().State; got != jobIdle {
+		t.Errorf("state before any job = %q", got)
+	}
+	StartGeocodeJob(context.Background()).Wait()
+	waitForFlights()
+
+	p := GeocodeStatus()
+	if p.State != jobDone || p.Done != 3 || p.Total != 3 || p.Failed != 1 || p.Retrying != 1 {
+		t.Errorf("progress = %+v", p)
+	}
+	r := geoRetries["Atlantis, Nowhere"]
+	if r.Attempts != 1 || !r.NextTry.After(time.Now()) {
+		t.Errorf("retry state = %+v", r)
+	}
+
+	// nothing is due on the next run
+	StartGeocodeJob(context.Background()).Wait()
+	if total := GeocodeStatus().Total; total != 0 {
+		t.Errorf("second run total = %d, want 0", total)
+	}
+}
+
+func TestGeocodeJobCancel(t *testing.T) {
+	defer setupCache(t)()
+	defer setupJobs()()
+	defer setupRelations("paris-france", "berlin-germany", "rome-italy", "oslo-norway", "lima-peru", "quito-ecuador")()
+	release := make(chan struct{})
+	defer setupGeocoder(roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		<-release
+		return geoResponse(http.StatusOK, `[{"lat":"1","lon":"1"}]`, nil), nil
+	}))()
+
+	job := StartGeocodeJob(context.Background())
+	if again := StartGeocodeJob(context.Background()); again != job {
+		t.Error("a second job was started while one is running")
+	}
+	CancelGeocodeJob()
+	job.Wait()
+	close(release)
+	waitForFlights()
+
+	if p := GeocodeStatus(); p.State != jobCancelled || p.Done == p.Total {
+		t.Errorf("progress after cancel = %+v", p)
+	}
+}
+
+func TestGeocodeJobStopsWhenUnreachable(t *testing.T) {
+	defer setupCache(t)()
+	defer setupJobs()()
+	defer setupRelations("paris-france", "berlin-germany", "rome-italy", "oslo-norway", "lima-peru", "quito-ecuador")()
+	defer setupGeocoder(roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		return nil, errors.New("network unreachable")
+	}))()
+
+	StartGeocodeJob(context.Background()).Wait()
+	waitForFlights()
+
+	if p := GeocodeStatus(); p.State != jobStopped || p.Failed == 0 || p.Retrying == 0 {
+		t.Errorf("progress = %+v, want stopped with retries queued", p)
+	}
+	if !provisional("Berlin, Germany") && !provisional("Paris, France") {
+		t.Error("no gazetteer fallback was kept until a retry")
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, retryBaseDelay},
+		{2, 2 * retryBaseDelay},
+		{4, 8 * retryBaseDelay},
+		{100, retryMaxDelay},
+	}
+	for _, tt := range tests {
+		if got := retryDelay(tt.attempts); got != tt.want {
+			t.Errorf("retryDelay(%d) = %v, want %v", tt.attempts, got, tt.want)
+		}
+	}
+}
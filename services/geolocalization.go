@@ -3,21 +3,41 @@ package services
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"groupie-tracker/api"
+	"groupie-tracker/geo"
 	"groupie-tracker/models"
 	"net/http"
 	"net/url"
 	"os"
-	"sync"
+	"strconv"
+	"strings"
 	"time"
 )
 
-// memory cache
+// Nominatim usage policy: at most 1 request per second, an identifying
+// User-Agent, and backing off when the service says so.
 var (
-	geoCache  = make(map[string]models.Coordinates)
-	geoMutex  sync.RWMutex
-	cacheFile = "locations.json" // to store cached locations
+	nominatimURL      = "https://nominatim.openstreetmap.org/search"
+	geocoderContact   = os.Getenv("GEOCODER_CONTACT") // email or URL of whoever runs this instance
+	geoLimiter        = newRateLimiter(1, time.Second)
+	geoBreaker        = newCircuitBreaker(5, 10*time.Minute)
+	defaultRetryAfter = time.Minute
+	candidateLimit    = 5 // results requested per lookup, kept as alternatives when suspicious
+	// GEOCODER_OFFLINE skips the network and resolves from the gazetteer only
+	geocoderOffline = os.Getenv("GEOCODER_OFFLINE") != ""
+)
+
+var (
+	// errNoCoordinates means the service answered but knows no such place.
+	errNoCoordinates = errors.New("no coordinates found")
+	errOffline       = errors.New("geocoding service disabled")
+	// errNetwork wraps failures to reach the service at all.
+	errNetwork = errors.New("geocoding service unreachable")
+	// errFallback wraps the error of a lookup answered from the gazetteer
+	// instead: the coordinates are usable but only provisional.
+	errFallback = errors.New("resolved offline")
 )
 
 // InitGeoCache only loads the file. Background filling is started separately.
@@ -25,138 +45,303 @@ func InitGeoCache() {
 	loadCache()
 }
 
-func loadCache() {
-	file, err := os.Open(cacheFile)
-	if err != nil {
-		fmt.Println("No cache file found, starting with empty cache.")
-		return
-	}
-	defer file.Close()
-
-	geoMutex.Lock()
-	defer geoMutex.Unlock()
-	if err := json.NewDecoder(file).Decode(&geoCache); err == nil {
-		fmt.Printf("Loaded %d locations from cache.\n", len(geoCache))
-	}
+// Geocode processes a list of locations and returns their coordinates.
+// Stale entries are still served; FillCacheBackground refreshes them.
+func Geocode(locations []string) map[string]models.Coordinates {
+	return GeocodeContext(context.Background(), locations)
 }
 
-func saveCache() {
-	geoMutex.RLock()
-	data, err := json.MarshalIndent(geoCache, "", "  ")
-	geoMutex.RUnlock()
+// GeocodeContext is like Geocode but stops waiting for lookups once ctx is
+// done, returning whatever it has resolved so far. Lookups shared with other
+// callers keep running and still land in the cache.
+func GeocodeContext(ctx context.Context, locations []string) map[string]models.Coordinates {
+	results := make(map[string]models.Coordinates)
+
+	now := time.Now()
+	for _, loc := range locations {
+		if ctx.Err() != nil {
+			break
+		}
+		// Check Cache
+		entry, cached := lookupCache(loc)
+		if cached && !entry.NotFound {
+			results[loc] = entry.Coordinates
+			continue
+		}
+		if cached && !entry.stale(now) {
+			continue // failed recently, don't ask again yet
+		}
 
-	if err == nil {
-		// Ignore errors on save (non-critical)
-		_ = os.WriteFile(cacheFile, data, 0644)
+		// Fetch on demand if not in cache
+		if coord, err := lookupShared(ctx, loc, false); err == nil || errors.Is(err, errFallback) {
+			results[loc] = coord
+		}
 	}
+	return results
 }
 
-// FillCacheBackground iterates through all relations and fetches missing coordinates.
-// It uses formatLocationName to ensure keys match the frontend requests.
-func FillCacheBackground() {
-	fmt.Println("Starting background geocoding...")
-
-	uniqueLocs := make(map[string]bool)
-
-	// Collect all unique formatted locations
-	for _, rel := range api.All_Relations {
-		for rawLoc := range rel.DatesLocations {
-			formatted := formatLocationName(rawLoc)
-			uniqueLocs[formatted] = true
+// MapPoints returns the locations that were resolved to coordinates, in
+// the order given. Locations missing from coords are left out rather than
+// drawn at 0°, 0°.
+func MapPoints(locations []string, coords map[string]models.Coordinates) []models.MapPoint {
+	points := make([]models.MapPoint, 0, len(locations))
+	for _, loc := range locations {
+		if c, ok := coords[loc]; ok {
+			points = append(points, models.MapPoint{Name: loc, Coordinates: c})
 		}
 	}
+	return points
+}
 
-	dirty := false
-	count := 0
-
-	for loc := range uniqueLocs {
-		// 1. Check Cache
-		geoMutex.RLock()
-		_, exists := geoCache[loc]
-		geoMutex.RUnlock()
-
-		if exists {
-			continue
+// lookupShared fetches loc through geoFlights so concurrent callers share
+// one request. The cache is checked again once the flight starts in case
+// another caller stored loc in the meantime; refresh skips that check for
+// entries that are only being re-validated, and for provisional ones.
+func lookupShared(ctx context.Context, loc string, refresh bool) (models.Coordinates, error) {
+	return geoFlights.Do(ctx, loc, func(ctx context.Context) (models.Coordinates, error) {
+		if entry, ok := lookupCache(loc); ok {
+			now := time.Now()
+			switch {
+			case !entry.NotFound && !(refresh && (entry.stale(now) || provisional(loc))):
+				return entry.Coordinates, nil
+			case entry.NotFound && !entry.stale(now):
+				return models.Coordinates{}, errNoCoordinates
+			}
+		}
+		entry, err := resolveLocation(ctx, loc)
+		if err != nil && ctx.Err() != nil {
+			return models.Coordinates{}, err // every caller gave up: nothing was learned
 		}
+		recordLookup(loc, entry, err)
+		return entry.Coordinates, err
+	})
+}
 
-		// 2. Fetch if missing
-		coord, err := fetchSingleCoordinate(loc)
-		if err == nil {
-			geoMutex.Lock()
-			geoCache[loc] = coord
-			geoMutex.Unlock()
-
-			dirty = true
-			count++
-			fmt.Printf("Cached: %s\n", loc)
-
-			// Save periodically to prevent data loss on crash
-			if count%5 == 0 {
-				saveCache()
-				dirty = false
-			}
-		} else {
-			fmt.Printf("Failed to fetch %s: %v\n", loc, err)
+// resolveLocation asks Nominatim and falls back to the embedded gazetteer
+// when the service can't be reached or is switched off with
+// GEOCODER_OFFLINE. Other failures, such as the service backing off or not
+// knowing the place, are returned as they are. A gazetteer answer comes
+// with the service's error wrapped in errFallback, so that it is cached
+// only provisionally and the lookup is retried.
+func resolveLocation(ctx context.Context, loc string) (geoEntry, error) {
+	err := errOffline
+	if !geocoderOffline {
+		entry, fetchErr := fetchSingleCoordinate(ctx, loc)
+		if fetchErr == nil || !errors.Is(fetchErr, errNetwork) {
+			return entry, fetchErr
 		}
+		err = fetchErr
 	}
-	// Final save
-	if dirty {
-		saveCache()
+	if place, ok := geo.Resolve(loc); ok {
+		entry := geoEntry{Coordinates: placeCoordinates(place), Source: sourceGazetteer}
+		// An unknown city resolves to the centre of its country
+		if _, country := geo.SplitLocation(loc); country != "" && place.Kind == geo.KindCountry {
+			entry.Approximate = true
+		}
+		return entry, fmt.Errorf("%w: %w", errFallback, err)
 	}
-	fmt.Println("Geolocalization background update complete.")
+	return geoEntry{}, err
 }
 
-// Geocode processes a list of locations and returns their coordinates.
-func Geocode(locations []string) map[string]models.Coordinates {
-	results := make(map[string]models.Coordinates)
+// placeCoordinates converts a gazetteer entry to cached coordinates.
+func placeCoordinates(place geo.Place) models.Coordinates {
+	display := place.Name
+	if c, ok := geo.CountryByCode(place.Country); ok && place.Kind != geo.KindCountry {
+		display += ", " + c.Name
+	}
+	return models.Coordinates{
+		Lat:         place.Lat,
+		Lon:         place.Lon,
+		DisplayName: display,
+		PlaceType:   models.PlaceType(place.Kind),
+	}
+}
 
-	for _, loc := range locations {
-		// Check Cache
-		geoMutex.RLock()
-		val, cached := geoCache[loc]
-		geoMutex.RUnlock()
+// nominatimResult is one entry of a Nominatim search response. Nominatim
+// sends coordinates and bounding boxes as strings.
+type nominatimResult struct {
+	Lat         string   `json:"lat"`
+	Lon         string   `json:"lon"`
+	DisplayName string   `json:"display_name"`
+	Class       string   `json:"class"`
+	Type        string   `json:"type"`
+	AddressType string   `json:"addresstype"`
+	PlaceRank   int      `json:"place_rank"`
+	Importance  float64  `json:"importance"`
+	BoundingBox []string `json:"boundingbox"`
+}
 
-		if cached {
-			results[loc] = val
-			continue
+// coordinates converts the result, failing if the position can't be parsed.
+func (r nominatimResult) coordinates() (models.Coordinates, error) {
+	lat, err := strconv.ParseFloat(r.Lat, 64)
+	if err != nil {
+		return models.Coordinates{}, fmt.Errorf("invalid latitude %q", r.Lat)
+	}
+	lon, err := strconv.ParseFloat(r.Lon, 64)
+	if err != nil {
+		return models.Coordinates{}, fmt.Errorf("invalid longitude %q", r.Lon)
+	}
+	coord := models.Coordinates{
+		Lat:         lat,
+		Lon:         lon,
+		DisplayName: r.DisplayName,
+		Class:       r.Class,
+		Type:        r.Type,
+		Importance:  r.Importance,
+		PlaceType:   placeTypeOf(r.AddressType, r.PlaceRank),
+	}
+	if len(r.BoundingBox) == 4 {
+		bbox := make([]float64, 4)
+		for i, v := range r.BoundingBox {
+			if bbox[i], err = strconv.ParseFloat(v, 64); err != nil {
+				bbox = nil
+				break
+			}
 		}
+		coord.BoundingBox = bbox
+	}
+	return coord, nil
+}
 
-		// Fetch on demand if not in cache
-		coord, err := fetchSingleCoordinate(loc)
-		if err == nil {
-			geoMutex.Lock()
-			geoCache[loc] = coord
-			geoMutex.Unlock()
-			results[loc] = coord
-			
-			go saveCache()
+// placeTypeOf classifies a Nominatim result from its address type, falling
+// back to the place rank (4 is a country, 5-12 states and counties).
+func placeTypeOf(addressType string, rank int) models.PlaceType {
+	switch addressType {
+	case "country":
+		return models.PlaceCountry
+	case "state", "province", "region", "territory", "county", "state_district":
+		return models.PlaceState
+	case "city", "town", "village", "municipality", "hamlet", "suburb", "borough", "city_district", "quarter", "neighbourhood":
+		return models.PlaceCity
+	}
+	switch {
+	case rank <= 0:
+		return ""
+	case rank <= 4:
+		return models.PlaceCountry
+	case rank <= 12:
+		return models.PlaceState
+	default:
+		return models.PlaceCity
+	}
+}
+
+// fetchSingleCoordinate looks loc up and returns the first candidate that
+// lies inside the named country. If none does, the first one is kept but
+// flagged as suspicious along with the alternatives for an admin to review.
+func fetchSingleCoordinate(ctx context.Context, loc string) (geoEntry, error) {
+	candidates, err := fetchCandidates(ctx, loc)
+	if err != nil {
+		return geoEntry{}, err
+	}
+	for _, c := range candidates {
+		if plausible(loc, c) {
+			return geoEntry{Coordinates: c, Source: sourceNominatim}, nil
 		}
 	}
-	return results
+	return geoEntry{
+		Coordinates: candidates[0],
+		Source:      sourceNominatim,
+		Suspicious:  true,
+		Candidates:  candidates,
+	}, nil
 }
 
-func fetchSingleCoordinate(loc string) (models.Coordinates, error) {
+// plausible reports whether coord lies inside the country named in loc.
+// Locations without a recognised country can't be checked and pass.
+func plausible(loc string, coord models.Coordinates) bool {
+	_, countryName := geo.SplitLocation(loc)
+	country, ok := geo.LookupCountry(countryName)
+	if !ok {
+		return true
+	}
+	return country.Contains(coord.Lat, coord.Lon)
+}
+
+// fetchCandidates asks Nominatim for up to candidateLimit results, limited to
+// the country named in loc when it is known. It gives up, waiting for its
+// turn or for the answer, once ctx is done.
+func fetchCandidates(ctx context.Context, loc string) ([]models.Coordinates, error) {
+	// Nominatim asks clients to stop while it is overloaded or refusing us
+	if err := geoBreaker.Allow(); err != nil {
+		return nil, err
+	}
+	// All geocoding callers share one limiter so the 1 request/second policy
+	// holds no matter how many page views trigger lookups at once
+	if err := geoLimiter.Wait(ctx); err != nil {
+		geoBreaker.Abandon()
+		return nil, err
+	}
+
 	// Short timeout prevents hanging requests
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	reqCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
-	baseURL := "https://nominatim.openstreetmap.org/search?format=json&limit=1&q="
-	req, err := http.NewRequestWithContext(ctx, "GET", baseURL+url.QueryEscape(loc), nil)
+	params := url.Values{}
+	params.Set("format", "json")
+	params.Set("limit", strconv.Itoa(candidateLimit))
+	params.Set("q", loc)
+	if _, countryName := geo.SplitLocation(loc); countryName != "" {
+		if country, ok := geo.LookupCountry(countryName); ok {
+			params.Set("countrycodes", strings.ToLower(country.Code))
+		}
+	}
+	if strings.Contains(geocoderContact, "@") {
+		params.Set("email", geocoderContact)
+	}
+	req, err := http.NewRequestWithContext(reqCtx, "GET", nominatimURL+"?"+params.Encode(), nil)
 	if err != nil {
-		return models.Coordinates{}, err
+		return nil, err
 	}
-	// User identity policy by Novatim
-	req.Header.Set("User-Agent", "GroupieTracker")
+	// User identity policy by Nominatim
+	req.Header.Set("User-Agent", geocoderUserAgent())
 
 	resp, err := api.Client.Do(req)
 	if err != nil {
-		return models.Coordinates{}, err
+		if ctx.Err() != nil {
+			geoBreaker.Abandon()
+			return nil, ctx.Err() // the caller gave up, the service didn't fail
+		}
+		geoBreaker.Failure()
+		return nil, fmt.Errorf("%w: %v", errNetwork, err)
 	}
 	defer resp.Body.Close()
 
-	var data []models.Coordinates
-	if json.NewDecoder(resp.Body).Decode(&data) == nil && len(data) > 0 {
-		return data[0], nil
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable:
+		// back off for as long as the server asks before anyone tries again
+		until := time.Now().Add(parseRetryAfter(resp.Header, defaultRetryAfter))
+		geoBreaker.Failure()
+		geoBreaker.OpenUntil(until)
+		geoLimiter.PauseUntil(until)
+		return nil, fmt.Errorf("geocoding service unavailable: status %d", resp.StatusCode)
+	case resp.StatusCode != http.StatusOK:
+		geoBreaker.Failure()
+		return nil, fmt.Errorf("geocoding service unexpected status: %d", resp.StatusCode)
 	}
-	return models.Coordinates{}, fmt.Errorf("no coordinates found")
-}
\ No newline at end of file
+	geoBreaker.Success()
+
+	var data []nominatimResult
+	var candidates []models.Coordinates
+	if json.NewDecoder(resp.Body).Decode(&data) == nil {
+		for _, r := range data {
+			if coord, err := r.coordinates(); err == nil {
+				candidates = append(candidates, coord)
+			}
+		}
+	}
+	if len(candidates) == 0 {
+		return nil, errNoCoordinates
+	}
+	return candidates, nil
+}
+
+// geocoderUserAgent identifies the application to Nominatim, including the
+// contact configured through GEOCODER_CONTACT when there is one.
+func geocoderUserAgent() string {
+	ua := "GroupieTracker/1.0 (+https://github.com/LeKoutz/groupie-tracker)"
+	if geocoderContact != "" {
+		ua = "GroupieTracker/1.0 (+https://github.com/LeKoutz/groupie-tracker; " + geocoderContact + ")"
+	}
+	return ua
+}
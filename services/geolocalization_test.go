@@ -0,0 +1,443 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"groupie-tracker/api"
+	"groupie-tracker/models"
+)
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+// setupGeocoder swaps the HTTP transport, limiter and breaker used by
+// fetchSingleCoordinate and returns a function restoring them.
+func setupGeocoder(rt http.RoundTripper) func() {
+	origTransport := api.Client.Transport
+	origLimiter, origBreaker := geoLimiter, geoBreaker
+	api.Client.Transport = rt
+	geoLimiter = newRateLimiter(10, time.Millisecond)
+	geoBreaker = newCircuitBreaker(2, time.Hour)
+	return func() {
+		api.Client.Transport = origTransport
+		geoLimiter, geoBreaker = origLimiter, origBreaker
+	}
+}
+
+func geoResponse(status int, body string, header http.Header) *http.Response {
+	if header == nil {
+		header = make(http.Header)
+	}
+	return &http.Response{
+		StatusCode: status,
+		Body:       io.NopCloser(bytes.NewBufferString(body)),
+		Header:     header,
+	}
+}
+
+func TestRateLimiterSpacesCalls(t *testing.T) {
+	l := newRateLimiter(1, 20*time.Millisecond)
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if err := l.Wait(context.Background()); err != nil {
+			t.Fatalf("Wait returned %v", err)
+		}
+	}
+	// the first token is free, the next two must each wait an interval
+	if elapsed := time.Since(start); elapsed < 35*time.Millisecond {
+		t.Errorf("3 calls took %v, want at least ~40ms", elapsed)
+	}
+}
+
+func TestRateLimiterHonoursContext(t *testing.T) {
+	l := newRateLimiter(1, time.Hour)
+	l.Wait(context.Background())
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := l.Wait(ctx); err == nil {
+		t.Error("Wait should fail once the context expires")
+	}
+}
+
+func TestRateLimiterPause(t *testing.T) {
+	l := newRateLimiter(5, time.Millisecond)
+	l.PauseUntil(time.Now().Add(30 * time.Millisecond))
+	start := time.Now()
+	l.Wait(context.Background())
+	if elapsed := time.Since(start); elapsed < 25*time.Millisecond {
+		t.Errorf("Wait returned after %v during a pause", elapsed)
+	}
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	b := newCircuitBreaker(2, 20*time.Millisecond)
+	b.Failure()
+	if err := b.Allow(); err != nil {
+		t.Fatalf("breaker opened below threshold: %v", err)
+	}
+	b.Failure()
+	if err := b.Allow(); err != errCircuitOpen {
+		t.Fatalf("Allow() = %v, want errCircuitOpen", err)
+	}
+	time.Sleep(25 * time.Millisecond)
+	// half-open: one probe goes through, others wait for its outcome
+	if err := b.Allow(); err != nil {
+		t.Fatalf("probe not allowed after cooldown: %v", err)
+	}
+	if err := b.Allow(); err != errCircuitOpen {
+		t.Errorf("second caller allowed while probing")
+	}
+	b.Success()
+	if err := b.Allow(); err != nil {
+		t.Errorf("breaker still open after a successful probe: %v", err)
+	}
+}
+
+func TestCircuitBreakerAbandonedProbe(t *testing.T) {
+	b := newCircuitBreaker(1, 10*time.Millisecond)
+	b.Failure()
+	time.Sleep(15 * time.Millisecond)
+	if err := b.Allow(); err != nil {
+		t.Fatalf("probe not allowed after cooldown: %v", err)
+	}
+	// the probe's caller gave up before asking: someone else may probe
+	b.Abandon()
+	if err := b.Allow(); err != nil {
+		t.Errorf("probe not allowed after the first was abandoned: %v", err)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{"", time.Minute},
+		{"120", 2 * time.Minute},
+		{"soon", time.Minute},
+		{"Mon, 01 Jan 2001 00:00:00 GMT", 0},
+	}
+	for _, tt := range tests {
+		h := http.Header{}
+		if tt.value != "" {
+			h.Set("Retry-After", tt.value)
+		}
+		if got := parseRetryAfter(h, time.Minute); got != tt.want {
+			t.Errorf("parseRetryAfter(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestFetchSingleCoordinateIdentifiesClient(t *testing.T) {
+	var gotUA, gotEmail string
+	defer setupGeocoder(roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		gotUA = r.Header.Get("User-Agent")
+		gotEmail = r.URL.Query().Get("email")
+		return geoResponse(http.StatusOK, `[{"lat":"48.85","lon":"2.35"}]`, nil), nil
+	}))()
+	origContact := geocoderContact
+	geocoderContact = "ops@example.com"
+	defer func() { geocoderContact = origContact }()
+
+	coord, err := fetchSingleCoordinate(context.Background(), "Paris, France")
+	if err != nil || coord.Lat != 48.85 {
+		t.Fatalf("fetchSingleCoordinate = %v, %v", coord, err)
+	}
+	if !strings.HasPrefix(gotUA, "GroupieTracker/") || !strings.Contains(gotUA, "ops@example.com") {
+		t.Errorf("User-Agent %q does not identify the contact", gotUA)
+	}
+	if gotEmail != "ops@example.com" {
+		t.Errorf("email parameter = %q", gotEmail)
+	}
+}
+
+func TestFetchSingleCoordinateBacksOff(t *testing.T) {
+	calls := 0
+	defer setupGeocoder(roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		calls++
+		h := http.Header{}
+		h.Set("Retry-After", "3600")
+		return geoResponse(http.StatusTooManyRequests, "", h), nil
+	}))()
+
+	if _, err := fetchSingleCoordinate(context.Background(), "Paris, France"); err == nil {
+		t.Fatal("expected an error on 429")
+	}
+	// Retry-After must keep every caller away from the service
+	if _, err := fetchSingleCoordinate(context.Background(), "Paris, France"); err != errCircuitOpen {
+		t.Errorf("second call error = %v, want errCircuitOpen", err)
+	}
+	if calls != 1 {
+		t.Errorf("service was called %d times, want 1", calls)
+	}
+}
+
+func TestGeocodeSharesConcurrentLookups(t *testing.T) {
+	defer setupCache(t)()
+	var mu sync.Mutex
+	calls := 0
+	release := make(chan struct{})
+	defer setupGeocoder(roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		mu.Lock()
+		calls++
+		mu.Unlock()
+		<-release
+		return geoResponse(http.StatusOK, `[{"lat":"48.85","lon":"2.35"}]`, nil), nil
+	}))()
+
+	var wg sync.WaitGroup
+	results := make([]map[string]models.Coordinates, 10)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i] = Geocode([]string{"Paris, France"})
+		}(i)
+	}
+	time.Sleep(20 * time.Millisecond) // let every caller join the flight
+	close(release)
+	wg.Wait()
+
+	if calls != 1 {
+		t.Errorf("service was called %d times, want 1", calls)
+	}
+	for i, res := range results {
+		if res["Paris, France"].Lat != 48.85 {
+			t.Errorf("caller %d got %v", i, res)
+		}
+	}
+}
+
+func TestGeocodeContextCancelledCallerDoesNotCancelLookup(t *testing.T) {
+	defer setupCache(t)()
+	release := make(chan struct{})
+	defer setupGeocoder(roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		<-release
+		return geoResponse(http.StatusOK, `[{"lat":"48.85","lon":"2.35"}]`, nil), nil
+	}))()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if res := GeocodeContext(ctx, []string{"Paris, France"}); len(res) != 0 {
+		t.Errorf("cancelled caller got %v", res)
+	}
+
+	// a patient caller asking next gets the result
+	done := make(chan map[string]models.Coordinates)
+	go func() { done <- Geocode([]string{"Paris, France"}) }()
+	close(release)
+	if res := <-done; res["Paris, France"].Lon != 2.35 {
+		t.Errorf("second caller got %v", res)
+	}
+	waitForFlights()
+}
+
+func TestGeocodeContextCallersShareLookupUntilAllGiveUp(t *testing.T) {
+	defer setupCache(t)()
+	var calls int32
+	release := make(chan struct{})
+	defer setupGeocoder(roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return geoResponse(http.StatusOK, `[{"lat":"48.85","lon":"2.35"}]`, nil), nil
+	}))()
+
+	// a patient caller and an impatient one wait on the same lookup
+	done := make(chan map[string]models.Coordinates)
+	go func() { done <- Geocode([]string{"Paris, France"}) }()
+	for atomic.LoadInt32(&calls) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if res := GeocodeContext(ctx, []string{"Paris, France"}); len(res) != 0 {
+		t.Errorf("cancelled caller got %v", res)
+	}
+	close(release)
+	if res := <-done; res["Paris, France"].Lon != 2.35 {
+		t.Errorf("patient caller got %v", res)
+	}
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Errorf("made %d requests, want the one shared", n)
+	}
+	waitForFlights()
+}
+
+func TestGeocodeContextStopsWaitingForTurn(t *testing.T) {
+	defer setupCache(t)()
+	var calls int32
+	defer setupGeocoder(roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		atomic.AddInt32(&calls, 1)
+		return geoResponse(http.StatusOK, `[{"lat":"48.85","lon":"2.35"}]`, nil), nil
+	}))()
+	// the only token is taken: the next lookup would wait an hour for its turn
+	geoLimiter = newRateLimiter(1, time.Hour)
+	geoLimiter.Wait(context.Background())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if res := GeocodeContext(ctx, []string{"Paris, France"}); len(res) != 0 {
+		t.Errorf("cancelled caller got %v", res)
+	}
+	finished := make(chan struct{})
+	go func() {
+		waitForFlights()
+		close(finished)
+	}()
+	select {
+	case <-finished:
+	case <-time.After(time.Second):
+		t.Fatal("the lookup kept waiting for the rate limiter after its caller gave up")
+	}
+	if n := atomic.LoadInt32(&calls); n != 0 {
+		t.Errorf("made %d requests after the caller gave up", n)
+	}
+	if _, ok := lookupCache("Paris, France"); ok {
+		t.Error("a lookup nobody waited for was cached")
+	}
+	if _, queued := geoRetries["Paris, France"]; queued {
+		t.Error("a lookup nobody waited for was queued for a retry")
+	}
+}
+
+func TestFetchCandidatesCancelled(t *testing.T) {
+	defer setupGeocoder(roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		<-r.Context().Done()
+		return nil, r.Context().Err()
+	}))()
+	for i := 0; i < 2; i++ {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		_, err := fetchCandidates(ctx, "Paris, France")
+		cancel()
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("fetchCandidates = %v, want the caller's deadline", err)
+		}
+	}
+	// giving up isn't the service failing, twice or not
+	if err := geoBreaker.Allow(); err != nil {
+		t.Errorf("breaker opened by cancelled requests: %v", err)
+	}
+}
+
+// waitForFlights blocks until no lookup is in flight, so a test doesn't
+// restore globals under a detached lookup that is still finishing.
+func waitForFlights() {
+	for {
+		geoFlights.mu.Lock()
+		n := geoFlights.running
+		geoFlights.mu.Unlock()
+		if n == 0 {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestNominatimResultCoordinates(t *testing.T) {
+	r := nominatimResult{
+		Lat: "33.2588817", Lon: "-86.8295337",
+		DisplayName: "Alabama, United States",
+		Class:       "boundary", Type: "administrative",
+		AddressType: "state", PlaceRank: 8, Importance: 0.8,
+		BoundingBox: []string{"30.1", "35.0", "-88.4", "-84.8"},
+	}
+	coord, err := r.coordinates()
+	if err != nil {
+		t.Fatalf("coordinates() returned %v", err)
+	}
+	if coord.Lat != 33.2588817 || coord.PlaceType != models.PlaceState || coord.BoundingBox[3] != -84.8 {
+		t.Errorf("coordinates() = %+v", coord)
+	}
+	if _, err := (nominatimResult{Lat: "north", Lon: "1"}).coordinates(); err == nil {
+		t.Error("expected an error for an invalid latitude")
+	}
+}
+
+func TestPlaceTypeOf(t *testing.T) {
+	tests := []struct {
+		addressType string
+		rank        int
+		want        models.PlaceType
+	}{
+		{"city", 16, models.PlaceCity},
+		{"state", 8, models.PlaceState},
+		{"country", 4, models.PlaceCountry},
+		{"", 4, models.PlaceCountry},
+		{"", 10, models.PlaceState},
+		{"", 18, models.PlaceCity},
+		{"", 0, ""},
+	}
+	for _, tt := range tests {
+		if got := placeTypeOf(tt.addressType, tt.rank); got != tt.want {
+			t.Errorf("placeTypeOf(%q, %d) = %q, want %q", tt.addressType, tt.rank, got, tt.want)
+		}
+	}
+}
+
+func TestFetchSingleCoordinateRestrictsCountry(t *testing.T) {
+	var gotCountry, gotLimit string
+	defer setupGeocoder(roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		gotCountry = r.URL.Query().Get("countrycodes")
+		gotLimit = r.URL.Query().Get("limit")
+		// Birmingham, England first, then Birmingham, Alabama
+		return geoResponse(http.StatusOK, `[{"lat":"52.48","lon":"-1.89"},{"lat":"33.52","lon":"-86.81"}]`, nil), nil
+	}))()
+
+	entry, err := fetchSingleCoordinate(context.Background(), "Birmingham, USA")
+	if err != nil {
+		t.Fatalf("fetchSingleCoordinate returned %v", err)
+	}
+	if gotCountry != "us" || gotLimit != "5" {
+		t.Errorf("countrycodes = %q, limit = %q", gotCountry, gotLimit)
+	}
+	if entry.Lat != 33.52 || entry.Suspicious {
+		t.Errorf("entry = %+v, want the candidate inside the USA", entry)
+	}
+}
+
+func TestFetchSingleCoordinateFlagsSuspicious(t *testing.T) {
+	defer setupGeocoder(roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		return geoResponse(http.StatusOK, `[{"lat":"48.43","lon":"-123.37"},{"lat":"-37.8","lon":"144.9"}]`, nil), nil
+	}))()
+
+	entry, err := fetchSingleCoordinate(context.Background(), "Victoria, Canada")
+	if err != nil {
+		t.Fatalf("fetchSingleCoordinate returned %v", err)
+	}
+	if entry.Suspicious || entry.Lat != 48.43 {
+		t.Errorf("entry = %+v, want the Canadian candidate", entry)
+	}
+
+	entry, _ = fetchSingleCoordinate(context.Background(), "Victoria, Germany")
+	if !entry.Suspicious || len(entry.Candidates) != 2 || entry.Lat != 48.43 {
+		t.Errorf("entry = %+v, want the first candidate flagged with alternatives", entry)
+	}
+}
+
+func TestMapPointsSkipsMissingLocations(t *testing.T) {
+	coords := map[string]models.Coordinates{
+		"Paris, France":   {Lat: 48.85, Lon: 2.35},
+		"Berlin, Germany": {Lat: 52.52, Lon: 13.4},
+	}
+	got := MapPoints([]string{"Berlin, Germany", "Atlantis, Nowhere", "Paris, France"}, coords)
+	want := []models.MapPoint{
+		{Name: "Berlin, Germany", Coordinates: coords["Berlin, Germany"]},
+		{Name: "Paris, France", Coordinates: coords["Paris, France"]},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("MapPoints = %+v, want %+v", got, want)
+	}
+}
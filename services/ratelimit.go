@@ -0,0 +1,174 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// errCircuitOpen is returned when the geocoding service is being left alone
+// after repeated failures.
+var errCircuitOpen = errors.New("geocoding temporarily disabled after repeated failures")
+
+// rateLimiter is a token bucket shared by every caller that talks to the
+// same upstream service. Tokens refill continuously at one per interval up
+// to burst. The bucket can also be paused, e.g. when the server sends a
+// Retry-After header.
+type rateLimiter struct {
+	mu          sync.Mutex
+	tokens      float64
+	burst       float64
+	interval    time.Duration
+	last        time.Time
+	pausedUntil time.Time
+}
+
+func newRateLimiter(burst int, interval time.Duration) *rateLimiter {
+	return &rateLimiter{
+		tokens:   float64(burst),
+		burst:    float64(burst),
+		interval: interval,
+		last:     time.Now(),
+	}
+}
+
+// Wait blocks until a token is available or ctx is done.
+func (l *rateLimiter) Wait(ctx context.Context) error {
+	for {
+		l.mu.Lock()
+		now := time.Now()
+		// refill the bucket for the time elapsed since the last call
+		l.tokens += float64(now.Sub(l.last)) / float64(l.interval)
+		if l.tokens > l.burst {
+			l.tokens = l.burst
+		}
+		l.last = now
+
+		var wait time.Duration
+		if now.Before(l.pausedUntil) {
+			wait = l.pausedUntil.Sub(now)
+		} else if l.tokens >= 1 {
+			l.tokens--
+			l.mu.Unlock()
+			return nil
+		} else {
+			wait = time.Duration((1 - l.tokens) * float64(l.interval))
+		}
+		l.mu.Unlock()
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// PauseUntil stops handing out tokens until t and empties the bucket so
+// callers don't burst as soon as the pause is over.
+func (l *rateLimiter) PauseUntil(t time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if t.After(l.pausedUntil) {
+		l.pausedUntil = t
+	}
+	l.tokens = 0
+	l.last = t
+}
+
+// circuitBreaker stops calls to a failing service. After threshold
+// consecutive failures it opens for cooldown; then a single probe call is
+// let through, and its outcome closes or re-opens the breaker.
+type circuitBreaker struct {
+	mu        sync.Mutex
+	failures  int
+	threshold int
+	cooldown  time.Duration
+	openUntil time.Time
+	probing   bool
+}
+
+func newCircuitBreaker(threshold int, cooldown time.Duration) *circuitBreaker {
+	return &circuitBreaker{threshold: threshold, cooldown: cooldown}
+}
+
+// Allow reports whether a call may be made right now.
+func (b *circuitBreaker) Allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.failures < b.threshold && b.openUntil.IsZero() {
+		return nil // closed
+	}
+	if time.Now().Before(b.openUntil) || b.probing {
+		return errCircuitOpen
+	}
+	// half-open: let exactly one probe through
+	b.probing = true
+	return nil
+}
+
+// Success resets the breaker.
+func (b *circuitBreaker) Success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures = 0
+	b.openUntil = time.Time{}
+	b.probing = false
+}
+
+// Failure records a failed call and opens the breaker once the threshold is hit.
+func (b *circuitBreaker) Failure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures++
+	b.probing = false
+	// a failed probe re-opens the breaker even below the threshold
+	if b.failures >= b.threshold || !b.openUntil.IsZero() {
+		b.openUntil = time.Now().Add(b.cooldown)
+	}
+}
+
+// Abandon gives back the probe slot of a call Allow let through but that
+// was never made, so the next call can probe instead.
+func (b *circuitBreaker) Abandon() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.failures >= b.threshold || !b.openUntil.IsZero() {
+		b.probing = false
+	}
+}
+
+// OpenUntil forces the breaker open until t (used for Retry-After).
+func (b *circuitBreaker) OpenUntil(t time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+	if t.After(b.openUntil) {
+		b.openUntil = t
+	}
+}
+
+// parseRetryAfter reads a Retry-After header given either as delay seconds
+// or as an HTTP date. It returns fallback if the header is missing or invalid.
+func parseRetryAfter(h http.Header, fallback time.Duration) time.Duration {
+	v := strings.TrimSpace(h.Get("Retry-After"))
+	if v == "" {
+		return fallback
+	}
+	if secs, err := strconv.Atoi(v); err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d
+		}
+		return 0
+	}
+	return fallback
+}
@@ -0,0 +1,71 @@
+package services
+
+import (
+	"context"
+	"groupie-tracker/models"
+	"sync"
+)
+
+// flightCall is a lookup in progress that other callers can wait on.
+type flightCall struct {
+	done    chan struct{}
+	cancel  context.CancelFunc
+	waiters int // callers still waiting for the result
+	coord   models.Coordinates
+	err     error
+}
+
+// flightGroup de-duplicates concurrent lookups of the same location: the
+// first caller starts the lookup and everyone else waits for its result.
+type flightGroup struct {
+	mu      sync.Mutex
+	calls   map[string]*flightCall
+	running int // lookups not finished yet, including abandoned ones
+}
+
+// geoFlights is shared by every geocoding caller.
+var geoFlights = &flightGroup{}
+
+// Do runs fn once per key at a time. A caller that gives up (ctx done)
+// stops waiting without cancelling the lookup for the others; the context
+// fn gets is cancelled only once every caller has given up, and a caller
+// coming after that starts a new lookup.
+func (g *flightGroup) Do(ctx context.Context, key string, fn func(context.Context) (models.Coordinates, error)) (models.Coordinates, error) {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = make(map[string]*flightCall)
+	}
+	c, inFlight := g.calls[key]
+	if !inFlight {
+		flightCtx, cancel := context.WithCancel(context.Background())
+		c = &flightCall{done: make(chan struct{}), cancel: cancel}
+		g.calls[key] = c
+		g.running++
+		go func() {
+			c.coord, c.err = fn(flightCtx)
+			cancel()
+			g.mu.Lock()
+			if g.calls[key] == c {
+				delete(g.calls, key)
+			}
+			g.running--
+			g.mu.Unlock()
+			close(c.done)
+		}()
+	}
+	c.waiters++
+	g.mu.Unlock()
+
+	select {
+	case <-c.done:
+		return c.coord, c.err
+	case <-ctx.Done():
+		g.mu.Lock()
+		if c.waiters--; c.waiters == 0 && g.calls[key] == c {
+			delete(g.calls, key)
+			c.cancel()
+		}
+		g.mu.Unlock()
+		return models.Coordinates{}, ctx.Err()
+	}
+}
@@ -0,0 +1,57 @@
+package services
+
+import (
+	"groupie-tracker/geo"
+	"groupie-tracker/models"
+	"time"
+)
+
+// LocalizeConcerts sets the timezone of every concert with ConcertTimeZone.
+func LocalizeConcerts(concerts []models.Concert, coords map[string]models.Coordinates) {
+	for i := range concerts {
+		concerts[i].TimeZone = ConcertTimeZone(concerts[i].Location, coords)
+	}
+}
+
+// ConcertTimeZone returns the timezone of a location from its coordinates,
+// or from its country when the location isn't mapped yet. Unknown places
+// are in UTC.
+func ConcertTimeZone(location string, coords map[string]models.Coordinates) string {
+	if coord, ok := coords[location]; ok {
+		return geo.LocationTimezone(location, coord.Lat, coord.Lon).String()
+	}
+	_, countryName := geo.SplitLocation(location)
+	if country, ok := geo.LookupCountry(countryName); ok {
+		return geo.TimezoneAt(country.Code, country.Lat, country.Lon)
+	}
+	return "UTC"
+}
+
+// concertZone loads the timezone of c, falling back to UTC.
+func concertZone(c models.Concert) *time.Location {
+	if c.TimeZone == "" {
+		return time.UTC
+	}
+	zone, err := time.LoadLocation(c.TimeZone)
+	if err != nil {
+		return time.UTC
+	}
+	return zone
+}
+
+// ConcertDay returns the instants the concert's local day starts and ends
+// (exclusive). Days are not always 24 hours long around DST changes.
+func ConcertDay(c models.Concert) (start, end time.Time) {
+	zone := concertZone(c)
+	y, m, d := c.Date.Date()
+	start = time.Date(y, m, d, 0, 0, 0, 0, zone)
+	end = time.Date(y, m, d+1, 0, 0, 0, 0, zone)
+	return start, end
+}
+
+// HappeningAt reports whether t falls on the concert's local day, e.g. to
+// tell whether a concert is happening today wherever it takes place.
+func HappeningAt(c models.Concert, t time.Time) bool {
+	start, end := ConcertDay(c)
+	return !t.Before(start) && t.Before(end)
+}
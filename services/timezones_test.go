@@ -0,0 +1,53 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"groupie-tracker/models"
+)
+
+func TestLocalizeConcerts(t *testing.T) {
+	concerts := []models.Concert{
+		{Location: "Los Angeles, USA"},
+		{Location: "Dallas, USA"},
+		{Location: "Lyon, France"},
+		{Location: "Atlantis, Nowhere"},
+	}
+	coords := map[string]models.Coordinates{
+		"Los Angeles, USA": {Lat: 34.05, Lon: -118.24},
+		"Dallas, USA":      {Lat: 32.78, Lon: -96.80},
+	}
+	LocalizeConcerts(concerts, coords)
+	want := []string{"America/Los_Angeles", "America/Chicago", "Europe/Paris", "UTC"}
+	for i, c := range concerts {
+		if c.TimeZone != want[i] {
+			t.Errorf("%s: zone %q, want %q", c.Location, c.TimeZone, want[i])
+		}
+	}
+}
+
+func TestConcertDay(t *testing.T) {
+	tokyo := models.Concert{Location: "Tokyo, Japan", Date: time.Date(2020, 3, 1, 0, 0, 0, 0, time.UTC), TimeZone: "Asia/Tokyo"}
+	start, end := ConcertDay(tokyo)
+	// midnight in Tokyo is 15:00 UTC the day before
+	if want := time.Date(2020, 2, 29, 15, 0, 0, 0, time.UTC); !start.Equal(want) || end.Sub(start) != 24*time.Hour {
+		t.Errorf("ConcertDay = %v - %v, want a day starting %v", start.UTC(), end.UTC(), want)
+	}
+
+	// the day clocks go forward in Paris is 23 hours long
+	paris := models.Concert{Date: time.Date(2020, 3, 29, 0, 0, 0, 0, time.UTC), TimeZone: "Europe/Paris"}
+	if start, end := ConcertDay(paris); end.Sub(start) != 23*time.Hour {
+		t.Errorf("DST day lasts %v, want 23h", end.Sub(start))
+	}
+
+	// 1 March in Tokyo is over by 10:00 on 1 March in Los Angeles
+	now := time.Date(2020, 3, 1, 1, 0, 0, 0, time.UTC)
+	if !HappeningAt(tokyo, now) || HappeningAt(tokyo, time.Date(2020, 3, 1, 15, 0, 0, 0, time.UTC)) {
+		t.Error("HappeningAt got the end of 1 March in Tokyo wrong")
+	}
+	la := models.Concert{Date: time.Date(2020, 3, 1, 0, 0, 0, 0, time.UTC), TimeZone: "America/Los_Angeles"}
+	if HappeningAt(la, now) || !HappeningAt(la, time.Date(2020, 3, 2, 7, 59, 0, 0, time.UTC)) {
+		t.Error("HappeningAt got the span of 1 March in Los Angeles wrong")
+	}
+}
@@ -0,0 +1,112 @@
+package services
+
+import (
+	"groupie-tracker/geo"
+	"groupie-tracker/models"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A break longer than tourGap between concerts starts a new tour, one
+// longer than legGap a new leg. Both can be set in days with TOUR_GAP_DAYS
+// and LEG_GAP_DAYS.
+var (
+	tourGap = envDays("TOUR_GAP_DAYS", 90)
+	legGap  = envDays("LEG_GAP_DAYS", 21)
+)
+
+// envDays reads a number of days from the environment, falling back to def
+// when the variable is unset or not a positive number.
+func envDays(name string, def int) time.Duration {
+	days, err := strconv.Atoi(os.Getenv(name))
+	if err != nil || days <= 0 {
+		days = def
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+// starredDates returns the dates the upstream dates endpoint prefixes with
+// '*'. The API marks the first date of every stop that way, so a date
+// without the marker continues the stop before it.
+func starredDates(dates *models.Dates) map[time.Time]bool {
+	starred := make(map[time.Time]bool)
+	if dates == nil {
+		return starred
+	}
+	for _, d := range dates.ConcertDates {
+		if !strings.HasPrefix(strings.TrimSpace(d), "*") {
+			continue
+		}
+		if t, err := parseDate(d); err == nil {
+			starred[t] = true
+		}
+	}
+	return starred
+}
+
+// SegmentTours splits chronologically ordered concerts into tours and legs
+// at breaks longer than tourGap and legGap. When dates carries '*' markers
+// a split only happens at a marked date, so a residency with a break in the
+// middle stays together. Without markers any concert can start a new leg.
+func SegmentTours(concerts []models.Concert, dates *models.Dates) []models.Tour {
+	starred := starredDates(dates)
+	canSplit := func(c models.Concert) bool {
+		return len(starred) == 0 || starred[c.Date]
+	}
+
+	var tours []models.Tour
+	for i, c := range concerts {
+		gap := time.Duration(0)
+		if i > 0 {
+			gap = c.Date.Sub(concerts[i-1].Date)
+		}
+		switch {
+		case i == 0 || (gap > tourGap && canSplit(c)):
+			tours = append(tours, models.Tour{Number: len(tours) + 1, Continents: []string{}, Legs: []models.Leg{{Number: 1}}})
+		case gap > legGap && canSplit(c):
+			tour := &tours[len(tours)-1]
+			tour.Legs = append(tour.Legs, models.Leg{Number: len(tour.Legs) + 1})
+		}
+		tour := &tours[len(tours)-1]
+		leg := &tour.Legs[len(tour.Legs)-1]
+		if len(leg.Concerts) == 0 {
+			leg.Start = c.Date
+		}
+		leg.End = c.Date
+		leg.Concerts = append(leg.Concerts, c)
+	}
+
+	for i := range tours {
+		tour := &tours[i]
+		tour.Start = tour.Legs[0].Start
+		tour.End = tour.Legs[len(tour.Legs)-1].End
+		seen := make(map[geo.Continent]bool)
+		for _, leg := range tour.Legs {
+			tour.Concerts += len(leg.Concerts)
+			for _, c := range leg.Concerts {
+				if continent := geo.ContinentOf(c.Location); continent != "" && !seen[continent] {
+					seen[continent] = true
+					tour.Continents = append(tour.Continents, string(continent))
+				}
+			}
+		}
+		sort.Strings(tour.Continents)
+	}
+	return tours
+}
+
+// AddTourDistances sums the hops of TourStats into the tours they belong
+// to. Hops from one tour to the next count towards neither.
+func AddTourDistances(tours []models.Tour, hops []models.Hop) {
+	for _, hop := range hops {
+		for i := range tours {
+			if !hop.FromDate.Before(tours[i].Start) && !hop.ToDate.After(tours[i].End) {
+				tours[i].Km += hop.Km
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"groupie-tracker/models"
+)
+
+func TestSegmentTours(t *testing.T) {
+	day := func(d int, m time.Month, y int) time.Time { return time.Date(y, m, d, 0, 0, 0, 0, time.UTC) }
+	concerts := []models.Concert{
+		{Location: "Paris, France", Date: day(1, 3, 2019)},
+		{Location: "Berlin, Germany", Date: day(5, 3, 2019)},
+		// a month off: new leg
+		{Location: "New York, USA", Date: day(10, 4, 2019)},
+		// half a year off: new tour
+		{Location: "Tokyo, Japan", Date: day(1, 11, 2019)},
+		// a residency with a break in the middle stays together
+		{Location: "Las Vegas, USA", Date: day(1, 12, 2019)},
+		{Location: "Las Vegas, USA", Date: day(1, 6, 2020)},
+	}
+	dates := &models.Dates{ConcertDates: []string{
+		"*01-03-2019", "*05-03-2019", "*10-04-2019", "*01-11-2019", "*01-12-2019", "01-06-2020",
+	}}
+
+	tours := SegmentTours(concerts, dates)
+	if len(tours) != 2 {
+		t.Fatalf("got %d tours, want 2: %+v", len(tours), tours)
+	}
+	first, second := tours[0], tours[1]
+	if first.Concerts != 3 || len(first.Legs) != 2 || !first.End.Equal(day(10, 4, 2019)) {
+		t.Errorf("first tour = %+v", first)
+	}
+	if want := []string{"Europe", "North America"}; !reflect.DeepEqual(first.Continents, want) {
+		t.Errorf("continents = %v, want %v", first.Continents, want)
+	}
+	if second.Number != 2 || second.Concerts != 3 || len(second.Legs) != 2 {
+		t.Errorf("second tour = %+v", second)
+	}
+	if last := second.Legs[1]; last.Number != 2 || len(last.Concerts) != 2 {
+		t.Errorf("residency leg = %+v, want both Las Vegas dates", last)
+	}
+
+	// without markers the residency break splits the tour
+	if tours := SegmentTours(concerts, nil); len(tours) != 3 {
+		t.Errorf("got %d tours without markers, want 3", len(tours))
+	}
+}
+
+func TestAddTourDistances(t *testing.T) {
+	day := func(d int, m time.Month, y int) time.Time { return time.Date(y, m, d, 0, 0, 0, 0, time.UTC) }
+	tours := []models.Tour{
+		{Start: day(1, 1, 2019), End: day(1, 2, 2019)},
+		{Start: day(1, 6, 2019), End: day(1, 7, 2019)},
+	}
+	AddTourDistances(tours, []models.Hop{
+		{FromDate: day(1, 1, 2019), ToDate: day(5, 1, 2019), Km: 100},
+		{FromDate: day(1, 2, 2019), ToDate: day(1, 6, 2019), Km: 5000}, // between tours
+		{FromDate: day(1, 6, 2019), ToDate: day(1, 7, 2019), Km: 200},
+	})
+	if tours[0].Km != 100 || tours[1].Km != 200 {
+		t.Errorf("tour distances = %.0f, %.0f", tours[0].Km, tours[1].Km)
+	}
+}
+
+func TestEnvDays(t *testing.T) {
+	t.Setenv("TEST_GAP_DAYS", "7")
+	if got := envDays("TEST_GAP_DAYS", 30); got != 7*24*time.Hour {
+		t.Errorf("envDays = %v, want 7 days", got)
+	}
+	t.Setenv("TEST_GAP_DAYS", "soon")
+	if got := envDays("TEST_GAP_DAYS", 30); got != 30*24*time.Hour {
+		t.Errorf("envDays with an invalid value = %v, want the default", got)
+	}
+}
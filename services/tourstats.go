@@ -0,0 +1,83 @@
+package services
+
+import (
+	"groupie-tracker/geo"
+	"groupie-tracker/models"
+	"sort"
+)
+
+// Concerts lists every concert in relations in chronological order. Dates
+// that can't be parsed are left out; concerts on the same date are ordered
+// by location so the result is stable.
+func Concerts(relations *models.Relations) []models.Concert {
+	var concerts []models.Concert
+	for loc, dates := range relations.DatesLocations {
+		for _, d := range dates {
+			if date, err := parseDate(d); err == nil {
+				concerts = append(concerts, models.Concert{Location: loc, Date: date})
+			}
+		}
+	}
+	sort.Slice(concerts, func(i, j int) bool {
+		if !concerts[i].Date.Equal(concerts[j].Date) {
+			return concerts[i].Date.Before(concerts[j].Date)
+		}
+		return concerts[i].Location < concerts[j].Location
+	})
+	return concerts
+}
+
+// TourStats computes great-circle travel between consecutive concerts.
+// Concerts at locations missing from coords are skipped, so the hop runs
+// from the previous mapped concert to the next one.
+func TourStats(concerts []models.Concert, coords map[string]models.Coordinates) models.TourStats {
+	stats := models.TourStats{Concerts: len(concerts), Hops: []models.Hop{}, Years: []models.YearStats{}}
+	years := make(map[int]*models.YearStats)
+	unmapped := make(map[string]bool)
+
+	var prev *models.Concert
+	var prevCoord models.Coordinates
+	for i := range concerts {
+		c := &concerts[i]
+		year := years[c.Date.Year()]
+		if year == nil {
+			year = &models.YearStats{Year: c.Date.Year()}
+			years[year.Year] = year
+		}
+		year.Concerts++
+
+		coord, ok := coords[c.Location]
+		if !ok {
+			if !unmapped[c.Location] {
+				unmapped[c.Location] = true
+				stats.Unmapped = append(stats.Unmapped, c.Location)
+			}
+			continue
+		}
+		if prev != nil {
+			hop := models.Hop{
+				From: prev.Location, To: c.Location,
+				FromDate: prev.Date, ToDate: c.Date,
+				Km: geo.DistanceKm(prevCoord.Lat, prevCoord.Lon, coord.Lat, coord.Lon),
+			}
+			stats.Hops = append(stats.Hops, hop)
+			stats.TotalKm += hop.Km
+			year.Km += hop.Km
+			if stats.Longest == nil || hop.Km > stats.Longest.Km {
+				longest := hop
+				stats.Longest = &longest
+			}
+		}
+		prev, prevCoord = c, coord
+	}
+
+	if len(stats.Hops) > 0 {
+		stats.AverageKm = stats.TotalKm / float64(len(stats.Hops))
+	}
+	for _, y := range years {
+		stats.Years = append(stats.Years, *y)
+	}
+	sort.Slice(stats.Years, func(i, j int) bool { return stats.Years[i].Year < stats.Years[j].Year })
+	sort.Strings(stats.Unmapped)
+	return stats
+}
@@ -0,0 +1,68 @@
+package services
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"groupie-tracker/models"
+)
+
+func TestConcerts(t *testing.T) {
+	relations := &models.Relations{DatesLocations: map[string][]string{
+		"Paris, France":   {"*02-01-2020", "bad date"},
+		"Berlin, Germany": {"01-01-2020", "02-01-2020"},
+	}}
+	got := Concerts(relations)
+	want := []models.Concert{
+		{Location: "Berlin, Germany", Date: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{Location: "Berlin, Germany", Date: time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC)},
+		{Location: "Paris, France", Date: time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC)},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("Concerts() = %+v", got)
+	}
+	for i := range want {
+		if got[i].Location != want[i].Location || !got[i].Date.Equal(want[i].Date) {
+			t.Errorf("concert %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestTourStats(t *testing.T) {
+	day := func(d int, m time.Month, y int) time.Time { return time.Date(y, m, d, 0, 0, 0, 0, time.UTC) }
+	concerts := []models.Concert{
+		{Location: "Paris, France", Date: day(30, 12, 2019)},
+		{Location: "Atlantis, Nowhere", Date: day(31, 12, 2019)},
+		{Location: "London, UK", Date: day(2, 1, 2020)},
+		{Location: "New York, USA", Date: day(10, 1, 2020)},
+	}
+	coords := map[string]models.Coordinates{
+		"Paris, France": {Lat: 48.8566, Lon: 2.3522},
+		"London, UK":    {Lat: 51.5074, Lon: -0.1278},
+		"New York, USA": {Lat: 40.7128, Lon: -74.0060},
+	}
+
+	stats := TourStats(concerts, coords)
+	if stats.Concerts != 4 || len(stats.Hops) != 2 {
+		t.Fatalf("stats = %+v", stats)
+	}
+	// Paris-London is about 344 km, London-New York about 5570 km
+	near := func(got, want float64) bool { return math.Abs(got-want) < 10 }
+	if !near(stats.Hops[0].Km, 344) || !near(stats.TotalKm, 344+5570) || !near(stats.AverageKm, (344+5570)/2) {
+		t.Errorf("distances: hops %+v, total %.0f, average %.0f", stats.Hops, stats.TotalKm, stats.AverageKm)
+	}
+	if stats.Longest == nil || stats.Longest.To != "New York, USA" {
+		t.Errorf("longest hop = %+v", stats.Longest)
+	}
+	if len(stats.Years) != 2 || stats.Years[0].Concerts != 2 || stats.Years[0].Km != 0 || !near(stats.Years[1].Km, stats.TotalKm) {
+		t.Errorf("years = %+v", stats.Years)
+	}
+	if len(stats.Unmapped) != 1 || stats.Unmapped[0] != "Atlantis, Nowhere" {
+		t.Errorf("unmapped = %v", stats.Unmapped)
+	}
+
+	if empty := TourStats(nil, coords); empty.TotalKm != 0 || empty.Longest != nil || empty.Hops == nil {
+		t.Errorf("stats without concerts = %+v", empty)
+	}
+}
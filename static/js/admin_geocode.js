@@ -0,0 +1,62 @@
+// Lets an admin drag a marker to correct the coordinates of a location.
+// "Map" on a row moves the marker there; dragging it fills that row's inputs.
+document.addEventListener("DOMContentLoaded", () => {
+  const mapElement = document.getElementById("admin-map");
+  if (!mapElement || typeof L === "undefined") {
+    return;
+  }
+
+  const map = L.map(mapElement).setView([20, 0], 2);
+  L.tileLayer("https://{s}.tile.openstreetmap.org/{z}/{x}/{y}.png", {
+    attribution: "&copy; OpenStreetMap &copy; Groupie Tracker",
+    subdomains: "abc",
+    maxZoom: 19,
+  }).addTo(map);
+
+  let marker = null;
+  let activeForm = null;
+
+  const fillInputs = (latlng) => {
+    if (!activeForm) {
+      return;
+    }
+    activeForm.querySelector("input[name='lat']").value = latlng.lat.toFixed(6);
+    activeForm.querySelector("input[name='lon']").value = latlng.lng.toFixed(6);
+  };
+
+  document.querySelectorAll(".admin-edit-map").forEach((button) => {
+    button.addEventListener("click", () => {
+      const form = button.closest("form");
+      if (activeForm) {
+        activeForm.closest("tr").classList.remove("admin-active");
+      }
+      activeForm = form;
+      form.closest("tr").classList.add("admin-active");
+
+      const lat = parseFloat(form.querySelector("input[name='lat']").value);
+      const lon = parseFloat(form.querySelector("input[name='lon']").value);
+      const known = Number.isFinite(lat) && Number.isFinite(lon);
+      const position = known ? [lat, lon] : map.getCenter();
+
+      if (!marker) {
+        marker = L.marker(position, { draggable: true }).addTo(map);
+        marker.on("dragend", () => fillInputs(marker.getLatLng()));
+      } else {
+        marker.setLatLng(position);
+      }
+      map.setView(position, known ? 10 : map.getZoom());
+      if (!known) {
+        fillInputs(marker.getLatLng());
+      }
+      mapElement.scrollIntoView({ behavior: "smooth" });
+    });
+  });
+
+  // clicking the map moves the marker too
+  map.on("click", (e) => {
+    if (marker && activeForm) {
+      marker.setLatLng(e.latlng);
+      fillInputs(e.latlng);
+    }
+  });
+});
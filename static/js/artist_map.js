@@ -6,11 +6,9 @@ document.addEventListener("DOMContentLoaded", () => {
     if (!mapElement || !locations)
         return;
 
-    const validLocations = locations.filter(loc => {
-        const lat = parseFloat(loc.lat);
-        const lon = parseFloat(loc.lon);
-        return !isNaN(lat) && !isNaN(lon);
-    });
+    const validLocations = locations.filter(loc =>
+        Number.isFinite(loc.lat) && Number.isFinite(loc.lon)
+    );
 
     // initialize the map
     const map = L.map(mapElement).setView([20, 0], 2); // center the map at (20, 0) with zoom level 2
@@ -42,13 +40,17 @@ document.addEventListener("DOMContentLoaded", () => {
     const bounds = [];
 
     validLocations.forEach((location, index) => {
-        const lat = parseFloat(location.lat);
-        const lon = parseFloat(location.lon);
+        const lat = location.lat;
+        const lon = location.lon;
 
         const marker = L.marker([lat, lon]).addTo(map);
         // replace hyphens with spaces and convert to uppercase for the display name
         const displayName = location.name.replace(/[-]/g, ` `).toUpperCase();
-        marker.bindPopup(`<b>${index + 1}.${displayName}</b>`); // add the display name to the marker
+        // state and country results only mark the centroid of the region
+        const precision = location.placeType === "state" || location.placeType === "country"
+            ? `<br><small>approximate (${location.placeType} centroid)</small>`
+            : "";
+        marker.bindPopup(`<b>${index + 1}.${displayName}</b>${precision}`); // add the display name to the marker
 
         // Highlight path to next and previous location when clicked
         marker.on('popupopen', () => {
@@ -62,7 +64,7 @@ document.addEventListener("DOMContentLoaded", () => {
             // 2. PREVIOUS STEP (Blue Line)
             if (index > 0) {
                 const prevLoc = validLocations[index - 1];
-                L.polyline([[lat, lon], [parseFloat(prevLoc.lat), parseFloat(prevLoc.lon)]], {
+                L.polyline([[lat, lon], [prevLoc.lat, prevLoc.lon]], {
                     color: '#3498db', // Blue for "Previous"
                     weight: 3,
                     opacity: 0.7,
@@ -73,7 +75,7 @@ document.addEventListener("DOMContentLoaded", () => {
             // 3. NEXT STEP (Red Line)
             if (index < validLocations.length - 1) {
                 const nextLoc = validLocations[index + 1];
-                L.polyline([[lat, lon], [parseFloat(nextLoc.lat), parseFloat(nextLoc.lon)]], {
+                L.polyline([[lat, lon], [nextLoc.lat, nextLoc.lon]], {
                     color: '#ee0c0cff', // Red for "Next"
                     weight: 3,
                     opacity: 0.7,
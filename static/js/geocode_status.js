@@ -0,0 +1,53 @@
+// Polls the background geocoding status and shows its progress. A box
+// marked data-while-running is only shown while a job runs, and stops
+// polling once it's over.
+document.addEventListener("DOMContentLoaded", () => {
+  const statusBox = document.getElementById("geocode-status");
+  if (!statusBox) {
+    return;
+  }
+  const whileRunning = statusBox.hasAttribute("data-while-running");
+  let timer;
+
+  const formatETA = (seconds) => {
+    if (seconds < 60) {
+      return `${seconds}s`;
+    }
+    return `${Math.round(seconds / 60)} min`;
+  };
+
+  const poll = async () => {
+    let status;
+    try {
+      const res = await fetch("/api/geocode/status");
+      status = await res.json();
+    } catch (err) {
+      return; // try again on the next tick
+    }
+
+    if (status.state === "idle" || (whileRunning && status.state !== "running")) {
+      statusBox.hidden = true;
+      if (whileRunning) {
+        clearInterval(timer);
+      }
+      return;
+    }
+    let text = `Locating concerts: ${status.done}/${status.total}`;
+    if (status.failed > 0) {
+      text += `, ${status.failed} failed`;
+    }
+    if (status.state === "running" && status.etaSeconds > 0) {
+      text += ` (about ${formatETA(status.etaSeconds)} left)`;
+    } else if (status.state !== "running") {
+      text += ` (${status.state})`;
+    }
+    if (status.retrying > 0) {
+      text += `, ${status.retrying} queued for retry`;
+    }
+    statusBox.textContent = text;
+    statusBox.hidden = false;
+  };
+
+  timer = setInterval(poll, 2000);
+  poll();
+});
@@ -7,34 +7,148 @@ document.addEventListener("DOMContentLoaded", () => {
 
   let debounceTimer;
 
+  // Builds a result's label as text, with the matched parts of its value
+  // in <mark>. Highlights count runes, which Array.from splits strings into.
+  const renderLabel = (r) => {
+    const link = document.createElement("a");
+    link.href = `/artist/${encodeURIComponent(r.ID)}`;
+    const rest = r.Label.indexOf(" - ");
+    if (!r.Value || rest < 0) {
+      link.textContent = r.Label;
+      return link;
+    }
+    const runes = Array.from(r.Value);
+    let at = 0;
+    for (const span of r.Highlights || []) {
+      link.append(runes.slice(at, span.RuneStart).join(""));
+      const mark = document.createElement("mark");
+      mark.textContent = runes.slice(span.RuneStart, span.RuneEnd).join("");
+      link.append(mark);
+      at = span.RuneEnd;
+    }
+    link.append(runes.slice(at).join(""), r.Label.slice(rest));
+    return link;
+  };
+
+  // Facet values refine the search: a category picks it in the select,
+  // other values add their clause to the query
+  const renderFacets = (facets) => {
+    const box = document.createElement("div");
+    box.className = "search-facets";
+    const groups = [
+      ["Category", facets.categories],
+      ["Country", facets.countries],
+      ["Created in", facets.decades],
+      ["Members", facets.members],
+    ];
+    for (const [title, values] of groups) {
+      if (!values || values.length === 0) continue;
+      const group = document.createElement("div");
+      group.className = "facet-group";
+      const heading = document.createElement("h3");
+      heading.textContent = title;
+      const list = document.createElement("ul");
+      for (const v of values) {
+        const button = document.createElement("button");
+        button.type = "button";
+        button.className = "facet";
+        let label = v.value;
+        if (title === "Category") {
+          const option = categorySelect.querySelector(`option[value="${v.value}"]`);
+          label = option ? option.textContent : v.value;
+          button.classList.toggle("current", categorySelect.value === v.value);
+        } else if (title === "Members") {
+          label = v.value === "1" ? "Solo" : `${v.value} members`;
+        }
+        const count = document.createElement("span");
+        count.className = "facet-count";
+        count.textContent = v.count;
+        button.append(`${label} `, count);
+        button.addEventListener("click", () => {
+          if (title === "Category") {
+            categorySelect.value = v.value;
+          } else {
+            input.value = `${input.value.trim()} ${v.clause}`;
+          }
+          fetchResults();
+        });
+        const item = document.createElement("li");
+        item.append(button);
+        list.append(item);
+      }
+      group.append(heading, list);
+      box.append(group);
+    }
+    return box;
+  };
+
   const fetchResults =  () => {
     clearTimeout(debounceTimer);
 
     const query = input.value.trim();
     const category = categorySelect.value;
     if (!query) {
-      resultsBox.innerHTML = "";
+      resultsBox.replaceChildren();
       resultsBox.style.display = "none";
       return;
     }
 
     debounceTimer = setTimeout(async () => {
-      const res = await fetch(`/api/search?search=${encodeURIComponent(query)}&category=${encodeURIComponent(categorySelect.value)}`);
-      const results = await res.json();
+      // The form data includes the filters, which belong to the form too
+      const params = new URLSearchParams(new FormData(form));
+      params.set("search", query);
+      // A few results of each category keep the dropdown short and balanced
+      params.set("limit", "12");
+      params.set("per_category", "4");
+      const res = await fetch(`/api/search?${params}`);
+      const data = await res.json();
+      const results = data.results;
 
-      // If no results found show a message, otherwise show a dropdown list
-      if (!results || results.length === 0) {
-        resultsBox.innerHTML = `<p class="no-results">No results found</p>`;
+      // An invalid query comes back with an error message to show as text
+      if (!res.ok) {
+        const error = document.createElement("p");
+        error.className = "search-error";
+        error.textContent = data.error;
+        resultsBox.replaceChildren(error);
+      } else if (!results || results.length === 0) {
+        // If no results found show a message, otherwise show a dropdown list
+        const message = document.createElement("p");
+        message.className = "no-results";
+        message.textContent = "No results found";
+        resultsBox.replaceChildren(message);
+        // A corrected query searches again in one click
+        if (data.suggestion) {
+          const params = new URLSearchParams(new FormData(form));
+          params.set("search", data.suggestion.query);
+          const link = document.createElement("a");
+          link.href = `/?${params}`;
+          link.textContent = data.suggestion.query;
+          link.addEventListener("click", (e) => {
+            e.preventDefault();
+            input.value = data.suggestion.query;
+            fetchResults();
+          });
+          const hint = document.createElement("p");
+          hint.className = "did-you-mean";
+          hint.append("Did you mean ", link, "?");
+          resultsBox.append(hint);
+        }
       } else {
-        resultsBox.innerHTML = `
-          <ul>
-            ${results.map(r => `
-              <li>
-                <a href="/artist/${r.ID}">${r.Label}</a>
-              </li>
-            `).join("")}
-          </ul>
-        `;
+        const list = document.createElement("ul");
+        for (const r of results) {
+          const item = document.createElement("li");
+          item.append(renderLabel(r));
+          list.append(item);
+        }
+        resultsBox.replaceChildren(list);
+        if (data.next) {
+          const more = document.createElement("a");
+          more.className = "search-more";
+          more.href = `/?${new URLSearchParams(new FormData(form))}`;
+          more.textContent = "See all results";
+          resultsBox.append(more);
+        }
+        resultsBox.append(renderFacets(data.facets));
             }
 
       resultsBox.style.display = "block";
@@ -47,7 +161,7 @@ document.addEventListener("DOMContentLoaded", () => {
     if (!form.contains(e.target) && !resultsBox.contains(e.target)) {
       resultsBox.style.display = "none";
     } else {
-      if (resultsBox.innerHTML !== "") {
+      if (resultsBox.hasChildNodes()) {
         resultsBox.style.display = "block";
       }
     }
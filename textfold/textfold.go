@@ -0,0 +1,86 @@
+// Package textfold compares text typed with or without diacritics, as
+// search queries and place names are.
+package textfold
+
+import (
+	"strings"
+	"unicode"
+)
+
+// String lowercases s and strips it down to base letters, so that queries
+// and indexed text typed with or without diacritics compare equal:
+// "Motörhead", "MOTORHEAD" and "motorhead" all fold to "motorhead".
+//
+// Precomposed letters are decomposed with the table below, combining
+// marks are dropped, and letters with no decomposition that are commonly
+// typed as plain Latin ones (ß, ø, æ, ł...) are spelled out, also when
+// they are what a precomposed letter decomposes to (ǽ, ǿ).
+func String(s string) string {
+	var b strings.Builder
+	b.Grow(len(s))
+	for _, r := range s {
+		r = unicode.ToLower(r)
+		if unicode.Is(unicode.Mn, r) {
+			continue
+		}
+		if base, ok := foldBase[r]; ok {
+			r = base
+		}
+		if spelled, ok := foldSpecial[r]; ok {
+			b.WriteString(spelled)
+		} else {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// foldSpecial spells out letters that don't decompose.
+var foldSpecial = map[rune]string{
+	'ß': "ss", 'æ': "ae", 'œ': "oe", 'ø': "o", 'đ': "d",
+	'ð': "d", 'þ': "th", 'ħ': "h", 'ı': "i", 'ł': "l", 'ŀ': "l",
+	'ŧ': "t", 'ƒ': "f", 'ς': "σ", 'ſ': "s",
+}
+
+// foldBase maps lowercase precomposed letters to their base letter. It
+// is derived from the canonical decompositions (NFD) of Latin-1, Latin
+// Extended-A and B, Greek, Cyrillic and Latin Extended Additional.
+var foldBase = map[rune]rune{
+	'à': 'a', 'á': 'a', 'â': 'a', 'ã': 'a', 'ä': 'a', 'å': 'a', 'ç': 'c', 'è': 'e',
+	'é': 'e', 'ê': 'e', 'ë': 'e', 'ì': 'i', 'í': 'i', 'î': 'i', 'ï': 'i', 'ñ': 'n',
+	'ò': 'o', 'ó': 'o', 'ô': 'o', 'õ': 'o', 'ö': 'o', 'ù': 'u', 'ú': 'u', 'û': 'u',
+	'ü': 'u', 'ý': 'y', 'ÿ': 'y', 'ā': 'a', 'ă': 'a', 'ą': 'a', 'ć': 'c', 'ĉ': 'c',
+	'ċ': 'c', 'č': 'c', 'ď': 'd', 'ē': 'e', 'ĕ': 'e', 'ė': 'e', 'ę': 'e', 'ě': 'e',
+	'ĝ': 'g', 'ğ': 'g', 'ġ': 'g', 'ģ': 'g', 'ĥ': 'h', 'ĩ': 'i', 'ī': 'i', 'ĭ': 'i',
+	'į': 'i', 'ĵ': 'j', 'ķ': 'k', 'ĺ': 'l', 'ļ': 'l', 'ľ': 'l', 'ń': 'n', 'ņ': 'n',
+	'ň': 'n', 'ō': 'o', 'ŏ': 'o', 'ő': 'o', 'ŕ': 'r', 'ŗ': 'r', 'ř': 'r', 'ś': 's',
+	'ŝ': 's', 'ş': 's', 'š': 's', 'ţ': 't', 'ť': 't', 'ũ': 'u', 'ū': 'u', 'ŭ': 'u',
+	'ů': 'u', 'ű': 'u', 'ų': 'u', 'ŵ': 'w', 'ŷ': 'y', 'ź': 'z', 'ż': 'z', 'ž': 'z',
+	'ơ': 'o', 'ư': 'u', 'ǎ': 'a', 'ǐ': 'i', 'ǒ': 'o', 'ǔ': 'u', 'ǖ': 'u', 'ǘ': 'u',
+	'ǚ': 'u', 'ǜ': 'u', 'ǟ': 'a', 'ǡ': 'a', 'ǣ': 'æ', 'ǧ': 'g', 'ǩ': 'k', 'ǫ': 'o',
+	'ǭ': 'o', 'ǯ': 'ʒ', 'ǰ': 'j', 'ǵ': 'g', 'ǹ': 'n', 'ǻ': 'a', 'ǽ': 'æ', 'ǿ': 'ø',
+	'ȁ': 'a', 'ȃ': 'a', 'ȅ': 'e', 'ȇ': 'e', 'ȉ': 'i', 'ȋ': 'i', 'ȍ': 'o', 'ȏ': 'o',
+	'ȑ': 'r', 'ȓ': 'r', 'ȕ': 'u', 'ȗ': 'u', 'ș': 's', 'ț': 't', 'ȟ': 'h', 'ȧ': 'a',
+	'ȩ': 'e', 'ȫ': 'o', 'ȭ': 'o', 'ȯ': 'o', 'ȱ': 'o', 'ȳ': 'y', '΅': '¨', 'ΐ': 'ι',
+	'ά': 'α', 'έ': 'ε', 'ή': 'η', 'ί': 'ι', 'ΰ': 'υ', 'ϊ': 'ι', 'ϋ': 'υ', 'ό': 'ο',
+	'ύ': 'υ', 'ώ': 'ω', 'ϓ': 'ϒ', 'ϔ': 'ϒ', 'й': 'и', 'ѐ': 'е', 'ё': 'е', 'ѓ': 'г',
+	'ї': 'і', 'ќ': 'к', 'ѝ': 'и', 'ў': 'у', 'ѷ': 'ѵ', 'ӂ': 'ж', 'ӑ': 'а', 'ӓ': 'а',
+	'ӗ': 'е', 'ӛ': 'ә', 'ӝ': 'ж', 'ӟ': 'з', 'ӣ': 'и', 'ӥ': 'и', 'ӧ': 'о', 'ӫ': 'ө',
+	'ӭ': 'э', 'ӯ': 'у', 'ӱ': 'у', 'ӳ': 'у', 'ӵ': 'ч', 'ӹ': 'ы', 'ḁ': 'a', 'ḃ': 'b',
+	'ḅ': 'b', 'ḇ': 'b', 'ḉ': 'c', 'ḋ': 'd', 'ḍ': 'd', 'ḏ': 'd', 'ḑ': 'd', 'ḓ': 'd',
+	'ḕ': 'e', 'ḗ': 'e', 'ḙ': 'e', 'ḛ': 'e', 'ḝ': 'e', 'ḟ': 'f', 'ḡ': 'g', 'ḣ': 'h',
+	'ḥ': 'h', 'ḧ': 'h', 'ḩ': 'h', 'ḫ': 'h', 'ḭ': 'i', 'ḯ': 'i', 'ḱ': 'k', 'ḳ': 'k',
+	'ḵ': 'k', 'ḷ': 'l', 'ḹ': 'l', 'ḻ': 'l', 'ḽ': 'l', 'ḿ': 'm', 'ṁ': 'm', 'ṃ': 'm',
+	'ṅ': 'n', 'ṇ': 'n', 'ṉ': 'n', 'ṋ': 'n', 'ṍ': 'o', 'ṏ': 'o', 'ṑ': 'o', 'ṓ': 'o',
+	'ṕ': 'p', 'ṗ': 'p', 'ṙ': 'r', 'ṛ': 'r', 'ṝ': 'r', 'ṟ': 'r', 'ṡ': 's', 'ṣ': 's',
+	'ṥ': 's', 'ṧ': 's', 'ṩ': 's', 'ṫ': 't', 'ṭ': 't', 'ṯ': 't', 'ṱ': 't', 'ṳ': 'u',
+	'ṵ': 'u', 'ṷ': 'u', 'ṹ': 'u', 'ṻ': 'u', 'ṽ': 'v', 'ṿ': 'v', 'ẁ': 'w', 'ẃ': 'w',
+	'ẅ': 'w', 'ẇ': 'w', 'ẉ': 'w', 'ẋ': 'x', 'ẍ': 'x', 'ẏ': 'y', 'ẑ': 'z', 'ẓ': 'z',
+	'ẕ': 'z', 'ẖ': 'h', 'ẗ': 't', 'ẘ': 'w', 'ẙ': 'y', 'ẛ': 'ſ', 'ạ': 'a', 'ả': 'a',
+	'ấ': 'a', 'ầ': 'a', 'ẩ': 'a', 'ẫ': 'a', 'ậ': 'a', 'ắ': 'a', 'ằ': 'a', 'ẳ': 'a',
+	'ẵ': 'a', 'ặ': 'a', 'ẹ': 'e', 'ẻ': 'e', 'ẽ': 'e', 'ế': 'e', 'ề': 'e', 'ể': 'e',
+	'ễ': 'e', 'ệ': 'e', 'ỉ': 'i', 'ị': 'i', 'ọ': 'o', 'ỏ': 'o', 'ố': 'o', 'ồ': 'o',
+	'ổ': 'o', 'ỗ': 'o', 'ộ': 'o', 'ớ': 'o', 'ờ': 'o', 'ở': 'o', 'ỡ': 'o', 'ợ': 'o',
+	'ụ': 'u', 'ủ': 'u', 'ứ': 'u', 'ừ': 'u', 'ử': 'u', 'ữ': 'u', 'ự': 'u', 'ỳ': 'y',
+	'ỵ': 'y', 'ỷ': 'y', 'ỹ': 'y',
+}
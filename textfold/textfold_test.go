@@ -0,0 +1,28 @@
+package textfold
+
+import "testing"
+
+func TestFold(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"Motörhead", "motorhead"},
+		{"Beyoncé", "beyonce"},
+		{"ZÜRICH", "zurich"},
+		{"Straße", "strasse"},
+		{"Mø", "mo"},
+		{"Sigur Rós", "sigur ros"},
+		{"Œuvre Æon", "oeuvre aeon"},
+		{"Łódź", "lodz"},
+		{"Ψυχή", "ψυχη"},
+		{"Beyonce\u0301", "beyonce"}, // already decomposed
+		{"São Paulo-Brazil", "sao paulo-brazil"},
+		{"AC/DC 1973", "ac/dc 1973"},
+		{"ǽ ǣ Ǽ", "ae ae ae"}, // decompose to æ, which is spelled out
+		{"Ǿrsted", "orsted"},
+		{"ẛ", "s"},
+	}
+	for _, tt := range tests {
+		if got := String(tt.in); got != tt.want {
+			t.Errorf("String(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}